@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dadlang/dadl/pkg/export"
+	"github.com/dadlang/dadl/pkg/parser"
+	"github.com/spf13/cobra"
+)
+
+var (
+	depsFormat string
+	depsTarget string
+)
+
+var depsFormats = map[string]func(filePath string, dependencies *parser.Dependencies) string{
+	"list": depsList,
+	"make": depsMake,
+	"json": depsJSON,
+	"dot":  depsDot,
+}
+
+func init() {
+	depsCmd.Flags().StringVarP(&depsFormat, "format", "f", "list", "Output format {list|make|json|dot}")
+	depsCmd.Flags().StringVarP(&depsTarget, "target", "t", "", "Target of the Makefile rule, defaults to the document")
+	rootCmd.AddCommand(depsCmd)
+}
+
+var depsCmd = &cobra.Command{
+	Use:   "deps <file>",
+	Short: "Lists files the document depends on",
+	Long: `Lists files the document depends on.
+
+Dependencies include the schema, schemas of imported files, overlay bases and
+every file matched by group imports. Formats:
+  list - one file per line
+  make - Makefile rule with the document (or --target) depending on the files
+  json - files with the document that refers to them and import patterns
+  dot  - Graphviz graph, imports are labeled with the path they are parsed into`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("requires a file name")
+		}
+		if _, ok := depsFormats[depsFormat]; !ok {
+			return fmt.Errorf("invalid format specified: %s", depsFormat)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		depsHandler(args[0])
+	},
+}
+
+func depsHandler(filePath string) {
+	dependencies := &parser.Dependencies{}
+	if _, err := parseFile(filePath, parser.WithDependencies(dependencies)); err != nil {
+		println(err.Error())
+		return
+	}
+	fmt.Print(depsFormats[depsFormat](filePath, dependencies))
+}
+
+//dependencyFiles returns distinct files, the same file may be referred by several documents
+func dependencyFiles(dependencies *parser.Dependencies) []string {
+	files := []string{}
+	seen := map[string]bool{}
+	for _, dependency := range dependencies.Files {
+		if !seen[dependency.File] {
+			seen[dependency.File] = true
+			files = append(files, dependency.File)
+		}
+	}
+	return files
+}
+
+func depsList(filePath string, dependencies *parser.Dependencies) string {
+	var sb strings.Builder
+	for _, file := range dependencyFiles(dependencies) {
+		sb.WriteString(file + "\n")
+	}
+	return sb.String()
+}
+
+//depsMake writes a rule in the format of compiler generated depfiles,
+//empty rules for every dependency keep make working when a file is removed
+func depsMake(filePath string, dependencies *parser.Dependencies) string {
+	target := depsTarget
+	if target == "" {
+		target = filePath
+	}
+	files := dependencyFiles(dependencies)
+	var sb strings.Builder
+	sb.WriteString(makeEscape(target) + ":")
+	for _, file := range files {
+		sb.WriteString(" \\\n  " + makeEscape(file))
+	}
+	sb.WriteString("\n")
+	for _, file := range files {
+		sb.WriteString("\n" + makeEscape(file) + ":\n")
+	}
+	return sb.String()
+}
+
+func makeEscape(file string) string {
+	return strings.NewReplacer(" ", "\\ ", "#", "\\#", "$", "$$").Replace(file)
+}
+
+func depsJSON(filePath string, dependencies *parser.Dependencies) string {
+	return export.ToJSON(map[string]interface{}{
+		"document": filePath,
+		"files":    dependencies.Files,
+		"patterns": dependencies.Patterns,
+	}) + "\n"
+}
+
+func depsDot(filePath string, dependencies *parser.Dependencies) string {
+	var sb strings.Builder
+	sb.WriteString("digraph dependencies {\n")
+	sb.WriteString("  " + strconv.Quote(filePath) + ";\n")
+	for _, dependency := range dependencies.Files {
+		label := "@" + string(dependency.Kind)
+		if dependency.Kind == parser.DependencyImport {
+			label = dependency.Path
+		}
+		sb.WriteString(fmt.Sprintf("  %s -> %s [label=%s];\n", strconv.Quote(dependency.From), strconv.Quote(dependency.File), strconv.Quote(label)))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dadlang/dadl/pkg/diff"
+	"github.com/dadlang/dadl/pkg/export"
+	"github.com/dadlang/dadl/pkg/parser"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFormat   string
+	diffExitCode bool
+)
+
+func init() {
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text", "Format of the report {text|json}")
+	diffCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "Exit with status 1 when documents differ")
+	rootCmd.AddCommand(diffCmd)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old file> <new file>",
+	Short: "Compares data of two dadl files",
+	Long: `Compares data of two dadl files.
+
+Documents are compared after parsing so changes in formatting, teleports or
+imports that don't change the data are not reported.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errors.New("requires two file names")
+		}
+		if diffFormat != "text" && diffFormat != "json" {
+			return fmt.Errorf("invalid report format specified: %s", diffFormat)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		diffHandler(args[0], args[1])
+	},
+}
+
+func diffHandler(oldFilePath string, newFilePath string) {
+	oldTree, oldSources, err := parseFileWithSources(oldFilePath)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	newTree, newSources, err := parseFileWithSources(newFilePath)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+
+	changes := diff.Diff(oldTree, newTree)
+	diff.Locate(changes, oldSources, newSources)
+
+	if diffFormat == "json" {
+		fmt.Println(export.ToJSON(map[string]interface{}{"changes": changes}))
+	} else {
+		for _, change := range changes {
+			fmt.Println(change.String() + describePositions(change))
+		}
+	}
+	if diffExitCode && len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseFileWithSources(filePath string) (parser.Node, parser.SourceMap, error) {
+	sources := parser.SourceMap{}
+	tree, err := parseFile(filePath, parser.WithSourceMap(sources))
+	return tree, sources, err
+}
+
+func describePositions(change diff.Change) string {
+	switch {
+	case change.OldPosition != nil && change.NewPosition != nil:
+		return fmt.Sprintf("  (%v -> %v)", change.OldPosition, change.NewPosition)
+	case change.OldPosition != nil:
+		return fmt.Sprintf("  (%v)", change.OldPosition)
+	case change.NewPosition != nil:
+		return fmt.Sprintf("  (%v)", change.NewPosition)
+	}
+	return ""
+}
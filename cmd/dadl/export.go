@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 
 	"github.com/dadlang/dadl/pkg/export"
 	"github.com/dadlang/dadl/pkg/parser"
@@ -13,24 +12,37 @@ import (
 )
 
 var (
-	format  string
-	outFile string
+	format       string
+	outFile      string
+	exportPath   string
+	appendLists  bool
+	explainMerge bool
+	exportEnv    bool
+	resolveRefs  bool
 
-	formatChoices = map[string]func(map[string]interface{}) string{"json": export.ToJSON, "yaml": export.ToYAML}
+	formatChoices = map[string]func(interface{}) string{"json": export.ToJSON, "yaml": export.ToYAML}
 )
 
 func init() {
 	exportCmd.Flags().StringVarP(&format, "format", "f", "json", "Format of the exported file {json|yaml}")
 	exportCmd.Flags().StringVarP(&outFile, "out", "o", "", "Save exported data to a file")
+	exportCmd.Flags().StringVarP(&exportPath, "path", "p", ".", "Export only values matching given path")
+	exportCmd.Flags().BoolVar(&appendLists, "append-lists", false, "Append lists of overlays instead of replacing them")
+	exportCmd.Flags().BoolVar(&explainMerge, "explain-merge", false, "Print values overridden by overlays")
+	exportCmd.Flags().BoolVar(&exportEnv, "env", false, "Substitute ${env:NAME} and ${env:NAME:-default} with environment variables")
+	exportCmd.Flags().BoolVar(&resolveRefs, "resolve-refs", false, "Replace references with values they point to")
+	exportCmd.Flags().BoolVar(&watchFiles, "watch", false, "Export again every time the document or any file it depends on changes")
 	rootCmd.AddCommand(exportCmd)
 }
 
 var exportCmd = &cobra.Command{
-	Use:   "export",
+	Use:   "export <file> [overlay files]",
 	Short: "Exports configuration to given format",
-	Long:  `Exports configuration to given format.`,
+	Long: `Exports configuration to given format.
+
+When more than one file is given every next file is merged on top of the previous ones.`,
 	Args: func(cmd *cobra.Command, args []string) error {
-		if len(args) != 1 {
+		if len(args) < 1 {
 			return errors.New("requires a file name")
 		}
 		if _, ok := formatChoices[format]; !ok {
@@ -39,31 +51,51 @@ var exportCmd = &cobra.Command{
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
-		exportHandler(args[0])
+		exportHandler(args)
 	},
 }
 
-func exportHandler(filePath string) {
+func exportHandler(filePaths []string) {
+	if watchFiles {
+		watch(filePaths, func(option parser.Option) {
+			exportFiles(filePaths, option)
+		})
+		return
+	}
+	exportFiles(filePaths)
+}
+
+func exportFiles(filePaths []string, options ...parser.Option) {
 	exporter, _ := formatChoices[format]
 
-	file, err := os.Open(filePath)
+	report := parser.MergeReport{}
+	if exportEnv {
+		options = append(options, parser.WithEnvSubstitution(os.LookupEnv))
+	}
+	if resolveRefs {
+		options = append(options, parser.WithResolvedRefs())
+	}
+	tree, err := parseOverlays(filePaths, parser.MergeOptions{AppendLists: appendLists}, &report, options...)
 	if err != nil {
-		log.Fatal(err)
+		println(err.Error())
+		return
+	}
+	if explainMerge {
+		for _, override := range report {
+			fmt.Fprintln(os.Stderr, override)
+		}
 	}
-	defer file.Close()
-
-	p := parser.NewParser()
-	tree, err := p.Parse(file, parser.NewFSResourceProvider(filepath.Dir(filePath)))
 
+	result, err := selectPath(tree, exportPath)
 	if err != nil {
 		println(err.Error())
 		return
 	}
 
 	if outFile != "" {
-		saveToFile(outFile, exporter(tree))
+		saveToFile(outFile, exporter(result))
 	} else {
-		fmt.Print(exporter(tree))
+		fmt.Print(exporter(result))
 	}
 }
 
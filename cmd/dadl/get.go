@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dadlang/dadl/pkg/export"
+	"github.com/dadlang/dadl/pkg/query"
+	"github.com/spf13/cobra"
+)
+
+var getFormat string
+
+func init() {
+	getCmd.Flags().StringVarP(&getFormat, "format", "f", "json", "Format of non scalar values {json|yaml}")
+	rootCmd.AddCommand(getCmd)
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get <path> <file>",
+	Short: "Prints values matching given path",
+	Long: `Prints values matching given path.
+
+Path supports map keys (a.b), list indices (nodes[1]), wildcards (modules.*),
+recursive descent (..name) and predicates (nodes[?port==9042]).`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return errors.New("requires a path and a file name")
+		}
+		if _, ok := formatChoices[getFormat]; !ok {
+			return fmt.Errorf("invalid format specified: %s", getFormat)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		getHandler(args[1], args[0])
+	},
+}
+
+func getHandler(filePath string, treePath string) {
+	tree, err := parseFile(filePath)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+
+	matches, err := query.Select(tree, treePath)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	exporter := formatChoices[getFormat]
+	for _, m := range matches {
+		switch m.Value.(type) {
+		case map[string]interface{}, []interface{}:
+			fmt.Println(exporter(m.Value))
+		default:
+			fmt.Println(export.Value(m.Value))
+		}
+	}
+}
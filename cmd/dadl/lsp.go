@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/dadlang/dadl/pkg/lsp"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Starts language server for editor support",
+	Long: `Starts language server for editor support.
+
+The server implements Language Server Protocol over stdio. It publishes
+diagnostics, completes keys and enum values defined by the schema, shows
+types and doc comments on hover and resolves @schema paths and imports
+on go-to-definition.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log.SetOutput(ioutil.Discard)
+		if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dadlang/dadl/pkg/parser"
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+var (
+	cacheDir          string
+	remote            bool
+	sandboxRoot       string
+	sandboxExtensions []string
+)
+
+func init() {
+	defaultCacheDir := ""
+	if dir, err := os.UserCacheDir(); err == nil {
+		defaultCacheDir = filepath.Join(dir, "dadl")
+	}
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", defaultCacheDir, "Directory where remote schemas and imports are cached")
+	rootCmd.PersistentFlags().BoolVar(&remote, "remote", false, "Fetch remote schemas and imports over http and https, otherwise they are read only from the cache")
+	rootCmd.PersistentFlags().StringVar(&sandboxRoot, "sandbox", "", "Read only files inside given directory, remote resources and symbolic links pointing outside are rejected")
+	rootCmd.PersistentFlags().StringSliceVar(&sandboxExtensions, "allow-ext", nil, "Extensions of files allowed in the sandbox, e.g. .dad,.dads")
+}
+
+//withRemoteResources makes http and https resources available to documents, they are downloaded only with --remote
+func withRemoteResources(resources parser.ResourceProvider) parser.ResourceProvider {
+	options := []parser.HTTPOption{parser.HTTPCacheDir(cacheDir)}
+	if !remote {
+		options = append(options, parser.HTTPOffline())
+	}
+	return parser.NewHTTPResourceProvider(resources, options...)
+}
+
+func parseFile(filePath string, options ...parser.Option) (parser.Node, error) {
+	if archivePath, documentPath, ok := splitArchivePath(filePath); ok {
+		return parseArchiveFile(archivePath, documentPath, options...)
+	}
+	if sandboxRoot != "" {
+		return parseSandboxedFile(filePath, options...)
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	p := parser.NewParser(append([]parser.Option{parser.WithFileName(filePath)}, options...)...)
+	return p.Parse(file, withRemoteResources(parser.NewFSResourceProvider(filepath.Dir(filePath))))
+}
+
+//parseSandboxedFile parses document with all files, including the document itself, confined to the sandbox root
+func parseSandboxedFile(filePath string, options ...parser.Option) (parser.Node, error) {
+	resources, err := parser.NewSandboxedFSResourceProvider(sandboxRoot, parser.SandboxNoSymlinkEscapes(), parser.SandboxExtensions(sandboxExtensions...))
+	if err != nil {
+		return nil, err
+	}
+	absRoot, err := filepath.Abs(sandboxRoot)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	documentPath, err := filepath.Rel(absRoot, absPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := resources.GetResource(documentPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	p := parser.NewParser(append([]parser.Option{parser.WithFileName(filePath)}, options...)...)
+	return p.Parse(file, resources.ForResource(documentPath))
+}
+
+//splitArchivePath splits paths like bundle.zip#project.dad into the archive and the document inside it
+func splitArchivePath(filePath string) (string, string, bool) {
+	idx := strings.LastIndex(filePath, "#")
+	if idx < 0 {
+		return "", "", false
+	}
+	archivePath := filePath[:idx]
+	for _, ext := range []string{".zip", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(archivePath, ext) {
+			return archivePath, filePath[idx+1:], true
+		}
+	}
+	return "", "", false
+}
+
+//parseArchiveFile parses document stored in the archive, schema and imports are resolved inside the archive
+func parseArchiveFile(archivePath string, documentPath string, options ...parser.Option) (parser.Node, error) {
+	data, err := ioutil.ReadFile(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := parser.NewArchiveResourceProvider(archivePath, data)
+	if err != nil {
+		return nil, err
+	}
+	file, err := resources.GetResource(documentPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	p := parser.NewParser(append([]parser.Option{parser.WithFileName(documentPath)}, options...)...)
+	return p.Parse(file, withRemoteResources(resources.ForResource(documentPath)))
+}
+
+//parseOverlays parses every file and merges it on top of the previous ones
+func parseOverlays(filePaths []string, mergeOptions parser.MergeOptions, report *parser.MergeReport, options ...parser.Option) (parser.Node, error) {
+	var result parser.Node
+	options = append(options, parser.WithMergeOptions(mergeOptions), parser.WithMergeReport(report), parser.WithSchemaCache(parser.NewSchemaCache()))
+	for _, filePath := range filePaths {
+		tree, err := parseFile(filePath, options...)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = tree
+			continue
+		}
+		var overrides []parser.Override
+		result, overrides = parser.Merge(result, tree, mergeOptions)
+		for _, override := range overrides {
+			override.Source = filePath
+			*report = append(*report, override)
+		}
+	}
+	return result, nil
+}
+
+//selectPath returns value matching given path, multiple matches are returned as a list
+func selectPath(tree parser.Node, treePath string) (interface{}, error) {
+	matches, err := query.Select(tree, treePath)
+	if err != nil {
+		return nil, err
+	}
+	switch len(matches) {
+	case 0:
+		return nil, query.ErrNotFound
+	case 1:
+		return matches[0].Value, nil
+	}
+	values := make([]interface{}, len(matches))
+	for i, m := range matches {
+		values[i] = m.Value
+	}
+	return values, nil
+}
@@ -3,18 +3,18 @@ package main
 import (
 	"errors"
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/dadlang/dadl/pkg/export"
 	"github.com/dadlang/dadl/pkg/parser"
+	"github.com/dadlang/dadl/pkg/query"
 	"github.com/spf13/cobra"
 	"github.com/xlab/treeprint"
 )
 
 func init() {
+	printCmd.Flags().BoolVar(&watchFiles, "watch", false, "Print again every time the document or any file it depends on changes")
 	rootCmd.AddCommand(printCmd)
 }
 
@@ -38,35 +38,40 @@ var printCmd = &cobra.Command{
 }
 
 func printHandler(filePath string, treePath string) {
+	if watchFiles {
+		watch([]string{filePath}, func(option parser.Option) {
+			printFile(filePath, treePath, option)
+		})
+		return
+	}
+	printFile(filePath, treePath)
+}
 
-	file, err := os.Open(filePath)
+func printFile(filePath string, treePath string, options ...parser.Option) {
+	tree, err := parseFile(filePath, options...)
 	if err != nil {
-		log.Fatal(err)
+		println(err.Error())
+		return
 	}
-	defer file.Close()
-
-	p := parser.NewParser()
-	tree, err := p.Parse(file, parser.NewFSResourceProvider(filepath.Dir(filePath)))
 
+	matches, err := query.Select(tree, treePath)
 	if err != nil {
 		println(err.Error())
 		return
 	}
-
-	result, _ := filterTree(tree, treePath)
-	printTree(result, treePath)
-}
-
-func filterTree(root map[string]interface{}, filterPath string) (interface{}, error) {
-	if filterPath == "." {
-		return root, nil
+	switch len(matches) {
+	case 0:
+		println(query.ErrNotFound.Error())
+		return
+	case 1:
+		printTree(matches[0].Value, treePath)
+		return
 	}
-	var node interface{} = root
-	pathElements := strings.Split(filterPath, ".")
-	for _, pathElement := range pathElements {
-		node = node.(map[string]interface{})[pathElement]
+	result := map[string]interface{}{}
+	for _, m := range matches {
+		result[m.Path] = m.Value
 	}
-	return node, nil
+	printTree(result, treePath)
 }
 
 func printTree(root interface{}, rootName string) {
@@ -113,6 +118,6 @@ func addChild(tree treeprint.Tree, key string, value interface{}) treeprint.Tree
 			return tree.AddBranch(key + ": " + asString)
 		}
 	} else {
-		return tree.AddBranch(fmt.Sprintf("%s: %v", key, value))
+		return tree.AddBranch(fmt.Sprintf("%s: %v", key, export.Value(value)))
 	}
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dadlang/dadl/pkg/render"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templatesDir string
+	renderOutDir string
+)
+
+func init() {
+	renderCmd.Flags().StringVarP(&templatesDir, "templates", "t", "", "Directory with Go templates")
+	renderCmd.Flags().StringVarP(&renderOutDir, "out", "o", ".", "Directory of generated files")
+	renderCmd.MarkFlagRequired("templates")
+	rootCmd.AddCommand(renderCmd)
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render <file>",
+	Short: "Generates files from data with Go templates",
+	Long: `Generates files from data with Go templates.
+
+Every file of the templates directory is rendered with Go text/template,
+".tmpl" extension is removed from names of generated files. File names may
+contain {path} placeholders, e.g. "{modules.*}/{interactors.*}.go.tmpl" is
+rendered once for every interactor of every module with the interactor as
+the template data. Templates can use lower, upper, title, camel, pascal,
+snake, kebab, root, get, select, keys and base functions.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("requires a file name")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		renderHandler(args[0])
+	},
+}
+
+func renderHandler(filePath string) {
+	tree, err := parseFile(filePath)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	files, err := render.Dir(tree, templatesDir)
+	if err != nil {
+		println(err.Error())
+		return
+	}
+	if err := render.Write(files, renderOutDir); err != nil {
+		println(err.Error())
+		return
+	}
+	for _, file := range files {
+		fmt.Println(file.Path)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/dadlang/dadl/pkg/parser"
+)
+
+var (
+	watchFiles    bool
+	watchInterval = 500 * time.Millisecond
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+//watch runs the handler and runs it again every time any of the files the documents depend on changes.
+//Handler has to pass given option to the parser to record dependencies of parsed documents.
+func watch(filePaths []string, handler func(option parser.Option)) {
+	for {
+		dependencies := &parser.Dependencies{}
+		handler(parser.WithDependencies(dependencies))
+		snapshot := takeSnapshot(filePaths, dependencies)
+		for reflect.DeepEqual(snapshot, takeSnapshot(filePaths, dependencies)) {
+			time.Sleep(watchInterval)
+		}
+		fmt.Fprintln(os.Stderr, "change detected, parsing again")
+	}
+}
+
+//takeSnapshot describes state of the documents, their dependencies and files matching import patterns.
+//Missing files are described with zero state so that creating them is also detected.
+func takeSnapshot(filePaths []string, dependencies *parser.Dependencies) map[string]fileState {
+	snapshot := map[string]fileState{}
+	add := func(file string) {
+		if info, err := os.Stat(file); err == nil {
+			snapshot[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+		} else {
+			snapshot[file] = fileState{}
+		}
+	}
+	for _, file := range filePaths {
+		if archivePath, _, ok := splitArchivePath(file); ok {
+			//files inside the archive change together with the archive
+			file = archivePath
+		}
+		add(file)
+	}
+	for _, dependency := range dependencies.Files {
+		add(dependency.File)
+	}
+	for _, pattern := range dependencies.Patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, file := range matches {
+			add(file)
+		}
+	}
+	return snapshot
+}
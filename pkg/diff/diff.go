@@ -0,0 +1,161 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/dadlang/dadl/pkg/export"
+	"github.com/dadlang/dadl/pkg/parser"
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+//ChangeType describes kind of the change
+type ChangeType string
+
+//Change types
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed"
+)
+
+//Change describes a single difference between two trees
+type Change struct {
+	Type        ChangeType       `json:"type"`
+	Path        string           `json:"path"`
+	OldValue    interface{}      `json:"old,omitempty"`
+	NewValue    interface{}      `json:"new,omitempty"`
+	OldPosition *parser.Position `json:"oldPosition,omitempty"`
+	NewPosition *parser.Position `json:"newPosition,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Type {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", displayPath(c.Path), displayValue(c.NewValue))
+	case Removed:
+		return fmt.Sprintf("- %s: %s", displayPath(c.Path), displayValue(c.OldValue))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", displayPath(c.Path), displayValue(c.OldValue), displayValue(c.NewValue))
+}
+
+//Diff compares two parsed trees and returns list of changes ordered by path.
+//Maps are compared by key and lists by index.
+func Diff(oldTree interface{}, newTree interface{}) []Change {
+	changes := []Change{}
+	compare("", oldTree, newTree, &changes)
+	return changes
+}
+
+//Locate fills positions of the changes using source maps of compared documents
+func Locate(changes []Change, oldSources parser.SourceMap, newSources parser.SourceMap) {
+	for i := range changes {
+		if changes[i].Type != Added {
+			if pos, ok := oldSources.Lookup(changes[i].Path); ok {
+				changes[i].OldPosition = &pos
+			}
+		}
+		if changes[i].Type != Removed {
+			if pos, ok := newSources.Lookup(changes[i].Path); ok {
+				changes[i].NewPosition = &pos
+			}
+		}
+	}
+}
+
+func compare(path string, oldValue interface{}, newValue interface{}, changes *[]Change) {
+	switch oldValue := oldValue.(type) {
+	case map[string]interface{}:
+		if newValue, ok := newValue.(map[string]interface{}); ok {
+			compareMaps(path, oldValue, newValue, changes)
+			return
+		}
+	case []interface{}:
+		if newValue, ok := newValue.([]interface{}); ok {
+			compareLists(path, oldValue, newValue, changes)
+			return
+		}
+	}
+	if !equal(oldValue, newValue) {
+		*changes = append(*changes, Change{Type: Changed, Path: path, OldValue: oldValue, NewValue: newValue})
+	}
+}
+
+func compareMaps(path string, oldMap map[string]interface{}, newMap map[string]interface{}, changes *[]Change) {
+	keys := query.SortedKeys(oldMap)
+	for _, key := range query.SortedKeys(newMap) {
+		if _, ok := oldMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		oldValue, inOld := oldMap[key]
+		newValue, inNew := newMap[key]
+		childPath := query.Key(path, key)
+		switch {
+		case !inOld:
+			*changes = append(*changes, Change{Type: Added, Path: childPath, NewValue: newValue})
+		case !inNew:
+			*changes = append(*changes, Change{Type: Removed, Path: childPath, OldValue: oldValue})
+		default:
+			compare(childPath, oldValue, newValue, changes)
+		}
+	}
+}
+
+func compareLists(path string, oldList []interface{}, newList []interface{}, changes *[]Change) {
+	for i := 0; i < len(oldList) || i < len(newList); i++ {
+		childPath := query.Index(path, i)
+		switch {
+		case i >= len(oldList):
+			*changes = append(*changes, Change{Type: Added, Path: childPath, NewValue: newList[i]})
+		case i >= len(newList):
+			*changes = append(*changes, Change{Type: Removed, Path: childPath, OldValue: oldList[i]})
+		default:
+			compare(childPath, oldList[i], newList[i], changes)
+		}
+	}
+}
+
+func equal(a interface{}, b interface{}) bool {
+	if aInt, ok := a.(*big.Int); ok {
+		if bInt, ok := b.(*big.Int); ok {
+			return aInt.Cmp(bInt) == 0
+		}
+	}
+	if aFloat, ok := a.(*big.Float); ok {
+		if bFloat, ok := b.(*big.Float); ok {
+			return aFloat.Cmp(bFloat) == 0
+		}
+	}
+	if aTime, ok := a.(time.Time); ok {
+		if bTime, ok := b.(time.Time); ok {
+			//offsets and kinds of times are compared as well
+			return export.Value(aTime) == export.Value(bTime)
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func displayValue(value interface{}) string {
+	value = export.Value(value)
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(value); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
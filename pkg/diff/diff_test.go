@@ -0,0 +1,92 @@
+package diff
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dadlang/dadl/pkg/parser"
+)
+
+func TestDiff(t *testing.T) {
+	oldTree := map[string]interface{}{
+		"name":    "boutique",
+		"port":    8080,
+		"big":     big.NewInt(10),
+		"removed": true,
+		"list":    []interface{}{"a", "b", "c"},
+		"nested":  map[string]interface{}{"x": 1, "y": 2},
+	}
+	newTree := map[string]interface{}{
+		"name":   "boutique",
+		"port":   8081,
+		"big":    big.NewInt(10),
+		"added":  "value",
+		"list":   []interface{}{"a", "x"},
+		"nested": "flat",
+	}
+
+	expected := []Change{
+		{Type: Added, Path: "added", NewValue: "value"},
+		{Type: Changed, Path: "list[1]", OldValue: "b", NewValue: "x"},
+		{Type: Removed, Path: "list[2]", OldValue: "c"},
+		{Type: Changed, Path: "nested", OldValue: map[string]interface{}{"x": 1, "y": 2}, NewValue: "flat"},
+		{Type: Changed, Path: "port", OldValue: 8080, NewValue: 8081},
+		{Type: Removed, Path: "removed", OldValue: true},
+	}
+	got := Diff(oldTree, newTree)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("\nGOT:  %+v\nWANT: %+v", got, expected)
+	}
+	if changes := Diff(oldTree, oldTree); len(changes) != 0 {
+		t.Errorf("expected no changes, got: %+v", changes)
+	}
+}
+
+func TestDiffDocuments(t *testing.T) {
+	oldTree, oldSources := parseSample(t, "diff/old.dad")
+	newTree, newSources := parseSample(t, "diff/new.dad")
+
+	changes := Diff(oldTree, newTree)
+	Locate(changes, oldSources, newSources)
+
+	expected := []string{
+		"~ cassandra.nodes[1].port: 9042 -> 9043",
+		`+ cassandra.nodes[2]: {"host":"node3","port":9042}`,
+		"- cassandra.pass: admin123",
+		"+ cassandra.user: admin",
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+	for i, change := range changes {
+		if change.String() != expected[i] {
+			t.Errorf("GOT: %s WANT: %s", change.String(), expected[i])
+		}
+	}
+	if changes[0].OldPosition == nil || changes[0].OldPosition.Line != 4 || changes[0].NewPosition.Line != 4 {
+		t.Errorf("unexpected positions: %+v", changes[0])
+	}
+	if changes[3].OldPosition != nil || changes[3].NewPosition.Line != 7 {
+		t.Errorf("unexpected positions: %+v", changes[3])
+	}
+}
+
+func parseSample(t *testing.T, name string) (parser.Node, parser.SourceMap) {
+	fullPath := "../../samples/" + name
+	file, err := os.Open(fullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	sources := parser.SourceMap{}
+	p := parser.NewParser(parser.WithFileName(fullPath), parser.WithSourceMap(sources))
+	tree, err := p.Parse(file, parser.NewFSResourceProvider(filepath.Dir(fullPath)))
+	if err != nil {
+		t.Fatalf("could not parse %v, %v", name, err)
+	}
+	return tree, sources
+}
@@ -6,8 +6,8 @@ import (
 )
 
 //ToJSON exports tree to JSON format
-func ToJSON(tree map[string]interface{}) string {
-	result, err := json.MarshalIndent(tree, "", "  ")
+func ToJSON(tree interface{}) string {
+	result, err := json.MarshalIndent(Value(tree), "", "  ")
 	if err != nil {
 		log.Fatal(err)
 	}
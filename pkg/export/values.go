@@ -0,0 +1,75 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//Value returns copy of the tree with values that have no textual form in JSON and YAML formatted as ISO 8601 strings:
+//dates as 2006-01-02, times of day as 15:04:05, datetimes as RFC 3339 and durations as PT1H30M
+func Value(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			res[key] = Value(child)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(value))
+		for i, child := range value {
+			res[i] = Value(child)
+		}
+		return res
+	case time.Time:
+		return formatTime(value)
+	case time.Duration:
+		return formatDuration(value)
+	}
+	return value
+}
+
+//formatTime recognizes dates and times of day by UTC location used by the parser, times of day are in year 0
+func formatTime(t time.Time) string {
+	if t.Location() != time.UTC {
+		return t.Format(time.RFC3339Nano)
+	}
+	if t.Year() == 0 && t.YearDay() == 1 {
+		return t.Format("15:04:05.999999999")
+	}
+	if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+func formatDuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+	var sb strings.Builder
+	//absolute value of the smallest duration doesn't fit time.Duration
+	abs := uint64(d)
+	if d < 0 {
+		sb.WriteString("-")
+		abs = uint64(-(d + 1)) + 1
+	}
+	sb.WriteString("PT")
+	hours, abs := abs/uint64(time.Hour), abs%uint64(time.Hour)
+	minutes, abs := abs/uint64(time.Minute), abs%uint64(time.Minute)
+	seconds, nanos := abs/uint64(time.Second), abs%uint64(time.Second)
+	if hours > 0 {
+		fmt.Fprintf(&sb, "%dH", hours)
+	}
+	if minutes > 0 {
+		fmt.Fprintf(&sb, "%dM", minutes)
+	}
+	if nanos > 0 {
+		fmt.Fprintf(&sb, "%d.%s", seconds, strings.TrimRight(fmt.Sprintf("%09d", nanos), "0"))
+		sb.WriteString("S")
+	} else if seconds > 0 {
+		fmt.Fprintf(&sb, "%dS", seconds)
+	}
+	return sb.String()
+}
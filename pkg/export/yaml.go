@@ -7,8 +7,8 @@ import (
 )
 
 //ToYAML exports tree to YAML format
-func ToYAML(tree map[string]interface{}) string {
-	result, err := yaml.Marshal(tree)
+func ToYAML(tree interface{}) string {
+	result, err := yaml.Marshal(Value(tree))
 	if err != nil {
 		log.Fatal(err)
 	}
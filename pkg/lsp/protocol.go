@@ -0,0 +1,141 @@
+package lsp
+
+//Subset of the Language Server Protocol types used by the server,
+//see https://microsoft.github.io/language-server-protocol/specification
+
+//Position in a text document, character is an offset in UTF-16 code units
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+//Range in a text document
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+//Location inside a resource
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+//DiagnosticSeverity of a diagnostic
+type DiagnosticSeverity int
+
+//Diagnostic severities
+const (
+	SeverityError DiagnosticSeverity = 1
+)
+
+//Diagnostic describes a problem found in a document
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity"`
+	Source   string             `json:"source"`
+	Message  string             `json:"message"`
+}
+
+//PublishDiagnosticsParams of textDocument/publishDiagnostics notification
+type PublishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+//TextDocumentItem is a document transferred from the client
+type TextDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+//TextDocumentIdentifier identifies a document
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+//DidOpenTextDocumentParams of textDocument/didOpen notification
+type DidOpenTextDocumentParams struct {
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
+//TextDocumentContentChangeEvent contains full text of the document, only full synchronization is supported
+type TextDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+//DidChangeTextDocumentParams of textDocument/didChange notification
+type DidChangeTextDocumentParams struct {
+	TextDocument   TextDocumentIdentifier           `json:"textDocument"`
+	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+//DidCloseTextDocumentParams of textDocument/didClose notification
+type DidCloseTextDocumentParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+//TextDocumentPositionParams of completion, hover and definition requests
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+//CompletionItemKind of a completion item
+type CompletionItemKind int
+
+//Completion item kinds
+const (
+	CompletionProperty   CompletionItemKind = 10
+	CompletionEnumMember CompletionItemKind = 20
+)
+
+//CompletionItem is a single completion proposal
+type CompletionItem struct {
+	Label         string             `json:"label"`
+	Kind          CompletionItemKind `json:"kind"`
+	Detail        string             `json:"detail,omitempty"`
+	Documentation string             `json:"documentation,omitempty"`
+}
+
+//MarkupContent is a documentation in markdown
+type MarkupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+//Hover is a result of textDocument/hover request
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    *Range        `json:"range,omitempty"`
+}
+
+//InitializeResult is a result of initialize request
+type InitializeResult struct {
+	Capabilities ServerCapabilities `json:"capabilities"`
+	ServerInfo   ServerInfo         `json:"serverInfo"`
+}
+
+//ServerCapabilities lists features supported by the server
+type ServerCapabilities struct {
+	TextDocumentSync   int               `json:"textDocumentSync"`
+	CompletionProvider CompletionOptions `json:"completionProvider"`
+	HoverProvider      bool              `json:"hoverProvider"`
+	DefinitionProvider bool              `json:"definitionProvider"`
+}
+
+//CompletionOptions of the server
+type CompletionOptions struct {
+	TriggerCharacters []string `json:"triggerCharacters,omitempty"`
+}
+
+//ServerInfo describes the server
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+//textDocumentSyncFull means that the client sends full text of the document on every change
+const textDocumentSyncFull = 1
@@ -0,0 +1,303 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/dadlang/dadl/pkg/parser"
+)
+
+var (
+	fileRefRe = regexp.MustCompile(`^@(?:schema|overlay)\s+(\S+)`)
+	importRe  = regexp.MustCompile(`^\s*\[[^<\]]*<\s*(.+?)\s*\]\s*$`)
+)
+
+type server struct {
+	conn      *conn
+	documents map[string]string
+}
+
+//Serve handles Language Server Protocol messages read from in and writes responses to out
+//until exit notification is received or input is closed
+func Serve(in io.Reader, out io.Writer) error {
+	s := &server{conn: newConn(in, out), documents: map[string]string{}}
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if respErr, ok := err.(*responseError); ok {
+				s.conn.reply(nil, nil, respErr)
+				continue
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, err := s.handleSafely(msg)
+		if msg.ID != nil {
+			if err := s.conn.reply(msg.ID, result, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+//handleSafely reports panics of the handler as errors, a bug triggered by one message doesn't stop the server
+func (s *server) handleSafely(msg *message) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &responseError{Code: codeInternalError, Message: fmt.Sprintf("internal error: %v", r)}
+		}
+	}()
+	return s.handle(msg)
+}
+
+func (s *server) handle(msg *message) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync:   textDocumentSyncFull,
+				CompletionProvider: CompletionOptions{TriggerCharacters: []string{" "}},
+				HoverProvider:      true,
+				DefinitionProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "dadl"},
+		}, nil
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		s.documents[params.TextDocument.URI] = params.TextDocument.Text
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) > 0 {
+			s.documents[params.TextDocument.URI] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		}
+		return nil, s.publishDiagnostics(params.TextDocument.URI)
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.documents, params.TextDocument.URI)
+		return nil, s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params), nil
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if hover := s.hover(params); hover != nil {
+			return hover, nil
+		}
+		return nil, nil
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "initialized", "shutdown", "$/cancelRequest":
+		return nil, nil
+	}
+	if msg.ID == nil {
+		//unknown notifications are ignored
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+}
+
+func unmarshalParams(msg *message, params interface{}) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+//parse parses text of the document, schema and imports are read relatively to the document file.
+//Panics of the parser are reported as errors so that they are published as diagnostics.
+func parse(uri string, text string, outline parser.Outline) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("internal error: %v", r)
+		}
+	}()
+	path := uriToPath(uri)
+	p := parser.NewParser(parser.WithFileName(path), parser.WithOutline(outline))
+	_, err = p.Parse(strings.NewReader(text), parser.NewFSResourceProvider(filepath.Dir(path)))
+	return err
+}
+
+func (s *server) publishDiagnostics(uri string) error {
+	text := s.documents[uri]
+	lines := strings.Split(text, "\n")
+	diagnostics := []Diagnostic{}
+
+	outline := parser.Outline{}
+	if err := parse(uri, text, outline); err != nil {
+		//errors found in other files, e.g. imports, are reported at the line that refers to them
+		lineNo, column, message := outline.LastLine(), 0, err.Error()
+		if parseErr, ok := err.(parser.ParseError); ok && parseErr.GetFile() == uriToPath(uri) {
+			lineNo, column, message = parseErr.GetLine(), parseErr.GetColumn(), parseErr.GetReason()
+		}
+		line := lineNo - 1
+		if line < 0 {
+			line = 0
+		}
+		end := 0
+		if line < len(lines) {
+			end = utf16Length(lines[line])
+		}
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    Range{Start: Position{Line: line, Character: column}, End: Position{Line: line, Character: end}},
+			Severity: SeverityError,
+			Source:   "dadl",
+			Message:  message,
+		})
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+//completion proposes keys accepted by the parent node or values accepted by the node of the line
+func (s *server) completion(params TextDocumentPositionParams) []CompletionItem {
+	items := []CompletionItem{}
+	lines := strings.Split(s.documents[params.TextDocument.URI], "\n")
+	if params.Position.Line >= len(lines) {
+		return items
+	}
+	line := lines[params.Position.Line]
+	prefix := line[:byteOffset(line, params.Position.Character)]
+	trimmed := strings.TrimLeft(prefix, " \t")
+	if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "@") {
+		return items
+	}
+	if trimmed == "" {
+		//blank lines are skipped by the parser, placeholder key makes it describe the line
+		prefix += "_"
+	}
+
+	//lines below the cursor don't affect the schema of the line and may be invalid while editing
+	outline := parser.Outline{}
+	parse(params.TextDocument.URI, strings.Join(append(lines[:params.Position.Line:params.Position.Line], prefix), "\n"), outline)
+	schema := outline[params.Position.Line+1]
+	if schema == nil {
+		return items
+	}
+	if !strings.ContainsAny(trimmed, " \t") && len(schema.Keys) > 0 {
+		for _, key := range schema.Keys {
+			items = append(items, CompletionItem{Label: key, Kind: CompletionProperty})
+		}
+		return items
+	}
+	for _, value := range schema.Values {
+		items = append(items, CompletionItem{Label: value, Kind: CompletionEnumMember, Detail: schema.Type})
+	}
+	return items
+}
+
+//hover describes type, constraints and doc comment of the node defined in the line
+func (s *server) hover(params TextDocumentPositionParams) *Hover {
+	lines := strings.Split(s.documents[params.TextDocument.URI], "\n")
+	if params.Position.Line >= len(lines) {
+		return nil
+	}
+	outline := parser.Outline{}
+	parse(params.TextDocument.URI, strings.Join(lines[:params.Position.Line+1], "\n"), outline)
+	schema := outline[params.Position.Line+1]
+	if schema == nil || schema.Type == "" {
+		return nil
+	}
+	var sb strings.Builder
+	if schema.Path != "" {
+		sb.WriteString("**" + schema.Path + "** ")
+	}
+	sb.WriteString("`" + schema.Type + "`")
+	if schema.Doc != "" {
+		sb.WriteString("\n\n" + schema.Doc)
+	}
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: sb.String()}}
+}
+
+//definition resolves files referred by @schema, @overlay and [group < import] lines
+func (s *server) definition(params TextDocumentPositionParams) []Location {
+	locations := []Location{}
+	lines := strings.Split(s.documents[params.TextDocument.URI], "\n")
+	if params.Position.Line >= len(lines) {
+		return locations
+	}
+	line := strings.TrimRight(lines[params.Position.Line], "\r")
+	dir := filepath.Dir(uriToPath(params.TextDocument.URI))
+
+	var paths []string
+	if match := fileRefRe.FindStringSubmatch(line); match != nil {
+		if match[1] != "dadl" {
+			paths = []string{match[1]}
+		}
+	} else if match := importRe.FindStringSubmatch(line); match != nil {
+		paths, _ = parser.NewFSResourceProvider(dir).FindResources(match[1])
+		sort.Strings(paths)
+	}
+	for _, path := range paths {
+		locations = append(locations, Location{URI: pathToURI(filepath.Join(dir, path))})
+	}
+	return locations
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+//byteOffset converts offset in UTF-16 code units to offset in bytes
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+func utf16Length(line string) int {
+	length := 0
+	for len(line) > 0 {
+		r, size := utf8.DecodeRuneInString(line)
+		length += len(utf16.Encode([]rune{r}))
+		line = line[size:]
+	}
+	return length
+}
@@ -0,0 +1,210 @@
+package lsp
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+//client is a scripted LSP client talking to the server running in-process
+type client struct {
+	t             *testing.T
+	conn          *conn
+	nextID        int
+	notifications []*message
+	done          chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+	c := &client{t: t, conn: newConn(clientIn, clientOut), done: make(chan error, 1)}
+	go func() {
+		c.done <- Serve(serverIn, serverOut)
+		serverOut.Close()
+	}()
+	return c
+}
+
+func (c *client) request(method string, params interface{}, result interface{}) {
+	c.nextID++
+	id := json.RawMessage(strings.Repeat("1", c.nextID))
+	data, _ := json.Marshal(params)
+	if err := c.conn.write(&message{ID: &id, Method: method, Params: data}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg, err := c.conn.read()
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		if msg.ID == nil {
+			c.notifications = append(c.notifications, msg)
+			continue
+		}
+		if string(*msg.ID) != string(id) {
+			c.t.Fatalf("unexpected response id: %s", *msg.ID)
+		}
+		if msg.Error != nil {
+			c.t.Fatalf("%s failed: %v", method, msg.Error)
+		}
+		data, _ := json.Marshal(msg.Result)
+		if err := json.Unmarshal(data, result); err != nil {
+			c.t.Fatal(err)
+		}
+		return
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+//diagnostics waits for the next published diagnostics
+func (c *client) diagnostics() PublishDiagnosticsParams {
+	var msg *message
+	if len(c.notifications) > 0 {
+		msg, c.notifications = c.notifications[0], c.notifications[1:]
+	} else {
+		var err error
+		if msg, err = c.conn.read(); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	if msg.Method != "textDocument/publishDiagnostics" {
+		c.t.Fatalf("unexpected notification: %s", msg.Method)
+	}
+	var params PublishDiagnosticsParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		c.t.Fatal(err)
+	}
+	return params
+}
+
+func (c *client) exit() {
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	path, err := filepath.Abs("../../samples/lsp/lsp.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(path)
+	text := string(data)
+	c := newClient(t)
+
+	var initResult InitializeResult
+	c.request("initialize", map[string]interface{}{}, &initResult)
+	if !initResult.Capabilities.HoverProvider || initResult.Capabilities.TextDocumentSync != textDocumentSyncFull {
+		t.Errorf("unexpected capabilities: %+v", initResult.Capabilities)
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "dadl", Text: text}})
+	if diagnostics := c.diagnostics(); len(diagnostics.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics: %+v", diagnostics)
+	}
+
+	invalid := strings.Replace(text, "port 8080", "port http", 1)
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}, ContentChanges: []TextDocumentContentChangeEvent{{Text: invalid}}})
+	expected := []Diagnostic{{
+		Range:    Range{Start: Position{Line: 6, Character: 0}, End: Position{Line: 6, Character: 9}},
+		Severity: SeverityError,
+		Source:   "dadl",
+		Message:  "Invalid int value: http",
+	}}
+	if diagnostics := c.diagnostics(); !reflect.DeepEqual(diagnostics.Diagnostics, expected) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", diagnostics.Diagnostics, expected)
+	}
+
+	edited := strings.Replace(text, "port 8080", "port 8080\n\n[modules.cart.interactors.GetCart]\nname Get Cart\ntype m", 1)
+	c.notify("textDocument/didChange", DidChangeTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}, ContentChanges: []TextDocumentContentChangeEvent{{Text: edited}}})
+	c.diagnostics()
+
+	var items []CompletionItem
+	c.request("textDocument/completion", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 9, Character: 2}}, &items)
+	if labels := completionLabels(items); !reflect.DeepEqual(labels, []string{"name", "type"}) {
+		t.Errorf("unexpected keys: %v", labels)
+	}
+	c.request("textDocument/completion", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 10, Character: 6}}, &items)
+	if labels := completionLabels(items); !reflect.DeepEqual(labels, []string{"mutation", "query"}) {
+		t.Errorf("unexpected values: %v", labels)
+	}
+	c.request("textDocument/completion", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 5, Character: 0}}, &items)
+	if labels := completionLabels(items); !reflect.DeepEqual(labels, []string{"host", "port"}) {
+		t.Errorf("unexpected keys: %v", labels)
+	}
+
+	var hover Hover
+	c.request("textDocument/hover", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 6, Character: 1}}, &hover)
+	if hover.Contents.Value != "**server.port** `int 0..65535`\n\nNetwork port" {
+		t.Errorf("unexpected hover: %q", hover.Contents.Value)
+	}
+	c.request("textDocument/hover", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 4, Character: 1}}, &hover)
+	if hover.Contents.Value != "**server** `struct`\n\nServer settings" {
+		t.Errorf("unexpected hover: %q", hover.Contents.Value)
+	}
+
+	var locations []Location
+	c.request("textDocument/definition", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 0, Character: 10}}, &locations)
+	if len(locations) != 1 || locations[0].URI != pathToURI(filepath.Join(filepath.Dir(path), "lsp.dads")) {
+		t.Errorf("unexpected schema location: %+v", locations)
+	}
+	c.request("textDocument/definition", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 12, Character: 20}}, &locations)
+	if len(locations) != 1 || locations[0].URI != pathToURI(filepath.Join(filepath.Dir(path), "modules", "cart.dad")) {
+		t.Errorf("unexpected import location: %+v", locations)
+	}
+
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	c.diagnostics()
+	c.request("shutdown", nil, &json.RawMessage{})
+	c.exit()
+}
+
+func completionLabels(items []CompletionItem) []string {
+	labels := []string{}
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	return labels
+}
+
+func TestDocumentWithoutSchema(t *testing.T) {
+	uri := pathToURI(filepath.Join(t.TempDir(), "draft.dad"))
+	c := newClient(t)
+	var initResult InitializeResult
+	c.request("initialize", map[string]interface{}{}, &initResult)
+
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "dadl", Text: "server\n    port 8080\n"}})
+	expected := []Diagnostic{{
+		Range:    Range{Start: Position{Line: 0, Character: 0}, End: Position{Line: 0, Character: 6}},
+		Severity: SeverityError,
+		Source:   "dadl",
+		Message:  "missing @schema, it has to be declared before the first node",
+	}}
+	if diagnostics := c.diagnostics(); !reflect.DeepEqual(diagnostics.Diagnostics, expected) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", diagnostics.Diagnostics, expected)
+	}
+
+	//server keeps answering requests about the document
+	var items []CompletionItem
+	c.request("textDocument/completion", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 1, Character: 4}}, &items)
+	var hover json.RawMessage
+	c.request("textDocument/hover", TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: uri}, Position: Position{Line: 0, Character: 1}}, &hover)
+	c.request("shutdown", nil, &json.RawMessage{})
+	c.exit()
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+//NewZipResourceProvider creates new resource provider reading resources from zip archive
+func NewZipResourceProvider(r io.ReaderAt, size int64) (ResourceProvider, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return NewIOFSResourceProvider(archive, "."), nil
+}
+
+//NewTarGzResourceProvider creates new resource provider with regular files of gzip compressed tar archive.
+//The archive is read into memory.
+func NewTarGzResourceProvider(r io.Reader) (ResourceProvider, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := map[string]string{}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(archive)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(strings.TrimPrefix(header.Name, "/"))] = string(data)
+	}
+	return NewMemoryResourceProvider(files), nil
+}
+
+//NewArchiveResourceProvider creates resource provider for archive content, the format is detected by the file name
+//extension: .zip, .tar.gz or .tgz
+func NewArchiveResourceProvider(fileName string, data []byte) (ResourceProvider, error) {
+	switch {
+	case strings.HasSuffix(fileName, ".zip"):
+		return NewZipResourceProvider(bytes.NewReader(data), int64(len(data)))
+	case strings.HasSuffix(fileName, ".tar.gz"), strings.HasSuffix(fileName, ".tgz"):
+		return NewTarGzResourceProvider(bytes.NewReader(data))
+	}
+	return nil, fmt.Errorf("unsupported archive format: %s", fileName)
+}
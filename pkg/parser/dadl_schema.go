@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"bytes"
 	"errors"
 	"log"
 	"math/big"
@@ -54,7 +55,7 @@ func GetDadlSchema() DadlSchema {
 		},
 		structValue: &mapValue{
 			keyType:   keyType,
-			valueType: typeDef,
+			valueType: typeWithCommentDef,
 		},
 		structValueKey: "children",
 	}
@@ -82,6 +83,45 @@ func GetDadlSchema() DadlSchema {
 					},
 				},
 			},
+			{
+				optional:  true,
+				composite: true,
+				children: []formulaItem{
+					{
+						valueType: &stringValue{regex: "\\s+minLen\\s+"},
+					},
+					{
+						name:      "minLen",
+						valueType: &intValue{},
+					},
+				},
+			},
+			{
+				optional:  true,
+				composite: true,
+				children: []formulaItem{
+					{
+						valueType: &stringValue{regex: "\\s+maxLen\\s+"},
+					},
+					{
+						name:      "maxLen",
+						valueType: &intValue{},
+					},
+				},
+			},
+			{
+				optional:  true,
+				composite: true,
+				children: []formulaItem{
+					{
+						valueType: whitespace,
+					},
+					{
+						name:      "trim",
+						valueType: &stringValue{regex: "trim|notrim"},
+					},
+				},
+			},
 		},
 	}
 	identifierDef := &formulaValue{
@@ -94,7 +134,8 @@ func GetDadlSchema() DadlSchema {
 	intDef := &formulaValue{
 		formula: []formulaItem{
 			{
-				valueType: &stringValue{regex: "int"},
+				name:      "kind",
+				valueType: &stringValue{regex: "u?int(?:8|16|32|64)?"},
 			},
 			{
 				optional:  true,
@@ -105,6 +146,7 @@ func GetDadlSchema() DadlSchema {
 					},
 					{
 						name:      "min",
+						optional:  true,
 						valueType: &intValue{},
 					},
 					{
@@ -112,6 +154,7 @@ func GetDadlSchema() DadlSchema {
 					},
 					{
 						name:      "max",
+						optional:  true,
 						valueType: &intValue{},
 					},
 				},
@@ -130,6 +173,51 @@ func GetDadlSchema() DadlSchema {
 			{
 				valueType: &stringValue{regex: "number"},
 			},
+			{
+				optional:  true,
+				composite: true,
+				children: []formulaItem{
+					{
+						valueType: whitespace,
+					},
+					{
+						name:      "big",
+						valueType: &stringValue{regex: "big"},
+					},
+				},
+			},
+			{
+				optional:  true,
+				composite: true,
+				children: []formulaItem{
+					{
+						valueType: whitespace,
+					},
+					{
+						name:      "min",
+						optional:  true,
+						valueType: &stringValue{regex: numberRegex},
+					},
+					{
+						name:      "minExclusive",
+						optional:  true,
+						valueType: &stringValue{regex: "<"},
+					},
+					{
+						valueType: &constantValue{value: ".."},
+					},
+					{
+						name:      "maxExclusive",
+						optional:  true,
+						valueType: &stringValue{regex: "<"},
+					},
+					{
+						name:      "max",
+						optional:  true,
+						valueType: &stringValue{regex: numberRegex},
+					},
+				},
+			},
 		},
 	}
 	enumDef := &formulaValue{
@@ -325,10 +413,78 @@ func GetDadlSchema() DadlSchema {
 		},
 		structValue: &mapValue{
 			keyType:   keyType,
-			valueType: typeDef,
+			valueType: typeWithCommentDef,
 		},
 		structValueKey: "valueType.children",
 	}
+	refDef := &formulaValue{
+		formula: []formulaItem{
+			{
+				valueType: &stringValue{regex: "ref"},
+			},
+			{
+				valueType: &constantValue{value: "["},
+			},
+			{
+				name:      "target",
+				valueType: &stringValue{regex: "[^\\]]+"},
+			},
+			{
+				valueType: &constantValue{value: "]"},
+			},
+		},
+	}
+	timeDef := &formulaValue{
+		formula: []formulaItem{
+			{
+				name:      "kind",
+				valueType: &stringValue{regex: "datetime|date|time|duration"},
+			},
+			{
+				optional:  true,
+				composite: true,
+				children: []formulaItem{
+					{
+						valueType: whitespace,
+					},
+					{
+						name:      "min",
+						optional:  true,
+						valueType: &stringValue{regex: "(?:[^.\\s]|\\.[^.\\s])+"},
+					},
+					{
+						valueType: &constantValue{value: ".."},
+					},
+					{
+						name:      "max",
+						optional:  true,
+						valueType: &stringValue{regex: "(?:[^.\\s]|\\.[^.\\s])+"},
+					},
+				},
+			},
+		},
+	}
+	formatDef := &formulaValue{
+		formula: []formulaItem{
+			{
+				name:      "format",
+				valueType: &stringValue{regex: "email|uri|url|ipv4|ipv6|ip|cidr|hostname|uuid|semver"},
+			},
+			{
+				optional:  true,
+				composite: true,
+				children: []formulaItem{
+					{
+						valueType: whitespace,
+					},
+					{
+						name:      "schemes",
+						valueType: &stringValue{regex: "[A-Za-z][A-Za-z0-9+.-]*(?:\\|[A-Za-z][A-Za-z0-9+.-]*)*"},
+					},
+				},
+			},
+		},
+	}
 	customTypeRef := &formulaValue{
 		formula: []formulaItem{
 			{
@@ -392,7 +548,7 @@ func GetDadlSchema() DadlSchema {
 		textValueKey: "",
 		structValue: &mapValue{
 			keyType:   keyType,
-			valueType: typeDef,
+			valueType: typeWithCommentDef,
 		},
 		structValueKey: "childType.children",
 	}
@@ -429,6 +585,18 @@ func GetDadlSchema() DadlSchema {
 			Name:      "sequenceDef",
 			ValueType: sequenceDef,
 		},
+		{
+			Name:      "refDef",
+			ValueType: refDef,
+		},
+		{
+			Name:      "timeDef",
+			ValueType: timeDef,
+		},
+		{
+			Name:      "formatDef",
+			ValueType: formatDef,
+		},
 		{
 			Name:      "customTypeRef",
 			ValueType: customTypeRef,
@@ -487,6 +655,18 @@ func GetDadlSchema() DadlSchema {
 			Name:      "complexDef",
 			ValueType: complexDef,
 		},
+		{
+			Name:      "refDef",
+			ValueType: refDef,
+		},
+		{
+			Name:      "timeDef",
+			ValueType: timeDef,
+		},
+		{
+			Name:      "formatDef",
+			ValueType: formatDef,
+		},
 		{
 			Name:      "customTypeRef",
 			ValueType: customTypeRef,
@@ -556,7 +736,17 @@ func mapType(data map[string]interface{}) (abstractTypeDef, error) {
 	case "identifierDef":
 		result = &identifierTypeDef{}
 	case "stringDef":
-		result = &stringTypeDef{}
+		stringDef := &stringTypeDef{NoTrim: data["trim"] == "notrim"}
+		if data["regex"] != nil {
+			stringDef.Regex = data["regex"].(string)
+		}
+		if data["minLen"] != nil {
+			stringDef.MinLen = int(data["minLen"].(*big.Int).Int64())
+		}
+		if data["maxLen"] != nil {
+			stringDef.MaxLen = int(data["maxLen"].(*big.Int).Int64())
+		}
+		return stringDef, nil
 	case "intDef":
 		result = &intTypeDef{}
 		if data["min"] != nil {
@@ -568,13 +758,44 @@ func mapType(data map[string]interface{}) (abstractTypeDef, error) {
 	case "boolDef":
 		result = &boolTypeDef{}
 	case "numberDef":
-		result = &numberTypeDef{}
+		numberDef := &numberTypeDef{Big: data["big"] != nil, MinExclusive: data["minExclusive"] != nil, MaxExclusive: data["maxExclusive"] != nil}
+		for _, bound := range []struct {
+			key    string
+			target **big.Float
+		}{{"min", &numberDef.Min}, {"max", &numberDef.Max}} {
+			if data[bound.key] == nil {
+				continue
+			}
+			value, _, err := big.ParseFloat(data[bound.key].(string), 10, bigNumberPrecision, big.ToNearestEven)
+			if err != nil {
+				return nil, errors.New("Invalid number range bound: " + data[bound.key].(string))
+			}
+			*bound.target = value
+		}
+		return numberDef, nil
 	case "enumDef":
 		result = &enumTypeDef{}
 	case "listDef":
 		result = &listTypeDef{}
 	case "sequenceDef":
 		result = &sequenceTypeDef{}
+	case "refDef":
+		result = &refTypeDef{}
+	case "timeDef":
+		timeDef := &timeTypeDef{Kind: data["kind"].(string)}
+		if data["min"] != nil {
+			timeDef.Min = data["min"].(string)
+		}
+		if data["max"] != nil {
+			timeDef.Max = data["max"].(string)
+		}
+		return timeDef, nil
+	case "formatDef":
+		formatDef := &formatTypeDef{Format: data["format"].(string)}
+		if data["schemes"] != nil {
+			formatDef.Schemes = strings.Split(data["schemes"].(string), "|")
+		}
+		return formatDef, nil
 	case "customTypeRef":
 		result = &customTypeRef{}
 	case "formulaDef":
@@ -605,6 +826,7 @@ func mapType(data map[string]interface{}) (abstractTypeDef, error) {
 				}
 				structure.Children[key] = childType
 			}
+			structure.Docs = docComments(children)
 		}
 		return structure, nil
 	case "complexDef":
@@ -636,18 +858,9 @@ func mapType(data map[string]interface{}) (abstractTypeDef, error) {
 	return result, nil
 }
 
-func parseSchema(schemaName string, resources ResourceProvider) (DadlSchema, error) {
-
-	if schemaName == "dadl" {
-		return GetDadlSchema(), nil
-	}
-
-	p := NewParser()
-	file, err := resources.GetResource(schemaName)
-	if err != nil {
-		return nil, err
-	}
-	tree, err := p.Parse(file, resources)
+func parseSchema(data []byte, resources ResourceProvider, options ...Option) (DadlSchema, error) {
+	p := NewParser(options...)
+	tree, err := p.Parse(bytes.NewReader(data), resources)
 	if err != nil {
 		return nil, err
 	}
@@ -666,19 +879,35 @@ func parseSchema(schemaName string, resources ResourceProvider) (DadlSchema, err
 	}
 	log.Printf("Schema as object: %+v\n", schemaRoot)
 
-	resolver := newResolver(schemaRoot.Types)
+	resolver := newResolver(schemaRoot.Types, docComments(tree["types"]))
 
 	root, err := resolver.buildType(&structTypeDef{
 		Children: schemaRoot.Structure,
+		Docs:     docComments(tree["structure"]),
 	})
 
 	if err != nil {
 		return nil, err
 	}
+	resolver.prepare()
 	log.Printf("Schema: %+v\n", root)
 	return &dadlSchemaImpl{root: root}, nil
 }
 
+//docComments returns # comments of type definitions
+func docComments(typeDefs interface{}) map[string]string {
+	docs := map[string]string{}
+	asMap, _ := typeDefs.(map[string]interface{})
+	for key, value := range asMap {
+		if typeDef, ok := value.(map[string]interface{}); ok {
+			if comment, ok := typeDef["comment"].(string); ok && comment != "" {
+				docs[key] = comment
+			}
+		}
+	}
+	return docs
+}
+
 type schemRoot struct {
 	Types     map[string]abstractTypeDef
 	Structure map[string]abstractTypeDef
@@ -687,22 +916,50 @@ type schemRoot struct {
 type abstractTypeDef interface{}
 
 type stringTypeDef struct {
-	Regex string
+	Regex  string
+	MinLen int
+	MaxLen int
+	NoTrim bool
 }
 type identifierTypeDef struct{}
 type intTypeDef struct {
-	Min *big.Int
-	Max *big.Int
+	Kind string
+	Min  *big.Int
+	Max  *big.Int
+}
+type numberTypeDef struct {
+	Big          bool
+	Min          *big.Float
+	Max          *big.Float
+	MinExclusive bool
+	MaxExclusive bool
 }
-type numberTypeDef struct{}
 type boolTypeDef struct{}
 type structTypeDef struct {
 	Children map[string]abstractTypeDef
+	Docs     map[string]string
+}
+type refTypeDef struct {
+	Target string
 }
 type customTypeRef struct {
 	TypeName string
 }
 
+//timeTypeDef is a date, time, datetime or duration with optional bounds, it's shadowed by a custom type with the
+//same name
+type timeTypeDef struct {
+	Kind string
+	Min  string
+	Max  string
+}
+
+//formatTypeDef is a built-in string format, it's shadowed by a custom type with the same name
+type formatTypeDef struct {
+	Format  string
+	Schemes []string
+}
+
 type abstractFormulaItem interface {
 }
 
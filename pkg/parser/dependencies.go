@@ -0,0 +1,72 @@
+package parser
+
+//DependencyKind describes how a document refers to a file it depends on
+type DependencyKind string
+
+//Dependency kinds
+const (
+	DependencySchema  DependencyKind = "schema"
+	DependencyImport  DependencyKind = "import"
+	DependencyOverlay DependencyKind = "overlay"
+)
+
+//Dependency describes a file read while parsing a document
+type Dependency struct {
+	Kind DependencyKind `json:"kind"`
+	File string         `json:"file"`
+	From string         `json:"from"`
+	//Path of the node the imported file is parsed into
+	Path string `json:"path,omitempty"`
+}
+
+//Dependencies collects files read while parsing a document. File names are relative to the main document
+//the same way as names passed with WithFileName option.
+type Dependencies struct {
+	Files []Dependency `json:"files"`
+	//Patterns of group imports, files that begin to match them change the document
+	Patterns []string `json:"patterns"`
+}
+
+//WithDependencies records files read while parsing the document, including schemas and imported files
+func WithDependencies(dependencies *Dependencies) Option {
+	return func(p *Parser) {
+		p.dependencies = dependencies
+	}
+}
+
+func (d *Dependencies) add(dependency Dependency) {
+	if d == nil {
+		return
+	}
+	for _, existing := range d.Files {
+		if existing == dependency {
+			return
+		}
+	}
+	d.Files = append(d.Files, dependency)
+}
+
+func (d *Dependencies) addPattern(pattern string) {
+	if d == nil {
+		return
+	}
+	for _, existing := range d.Patterns {
+		if existing == pattern {
+			return
+		}
+	}
+	d.Patterns = append(d.Patterns, pattern)
+}
+
+//merge adds dependencies collected separately, e.g. by compiled schema or concurrently parsed file
+func (d *Dependencies) merge(other *Dependencies) {
+	if other == nil {
+		return
+	}
+	for _, dependency := range other.Files {
+		d.add(dependency)
+	}
+	for _, pattern := range other.Patterns {
+		d.addPattern(pattern)
+	}
+}
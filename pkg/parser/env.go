@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+)
+
+var envReferenceRe = regexp.MustCompile(`\$\$\{|\$\{env:([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+//EnvLookup returns value of the environment variable, see os.LookupEnv
+type EnvLookup func(name string) (string, bool)
+
+//WithEnvSubstitution enables substitution of ${env:NAME} and ${env:NAME:-default} in parsed lines.
+//Substitution happens before values are parsed so the result is checked against the schema.
+func WithEnvSubstitution(lookup EnvLookup) Option {
+	return func(p *Parser) {
+		p.envLookup = lookup
+	}
+}
+
+//substituteEnv replaces environment references of the line. When the line holds a value interpolated later,
+//escape makes the substituted text literal, so ${ and $${ in variables aren't taken for references and escapes.
+func (p *Parser) substituteEnv(line string, pos Position, escape bool) (string, error) {
+	if p.envLookup == nil || !strings.Contains(line, "${env:") {
+		return line, nil
+	}
+	var sb strings.Builder
+	//substituted text ending with $ would start a reference with { that follows it
+	openDollar := false
+	write := func(text string, substituted bool) {
+		if text == "" {
+			return
+		}
+		if openDollar && text[0] == '{' {
+			sb.WriteString("$")
+		}
+		if escape && substituted {
+			text = strings.ReplaceAll(text, "${", "$${")
+		}
+		sb.WriteString(text)
+		openDollar = escape && substituted && strings.HasSuffix(text, "$")
+	}
+	last := 0
+	for _, match := range envReferenceRe.FindAllStringSubmatchIndex(line, -1) {
+		write(line[last:match[0]], false)
+		last = match[1]
+		if match[2] < 0 {
+			write(line[match[0]:match[1]], false)
+			continue
+		}
+		name := line[match[2]:match[3]]
+		value, ok := p.envLookup(name)
+		if !ok {
+			if match[4] < 0 {
+				pos.Column = match[0]
+				return "", newParseErrorAt(pos, "missing environment variable: "+name)
+			}
+			value = line[match[4]:match[5]]
+		}
+		write(value, true)
+	}
+	write(line[last:], false)
+	return sb.String(), nil
+}
@@ -0,0 +1,287 @@
+package parser
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+//stringFormat is a built-in type of strings with well known syntax
+type stringFormat struct {
+	//regex finds candidates of the value inside formulas, only the parser decides if the value is valid
+	regex string
+	//parse validates the value and returns its normalized form
+	parse func(value string) (string, error)
+}
+
+const (
+	ipv4Regex = "[0-9]{1,3}(?:\\.[0-9]{1,3}){3}"
+	ipv6Regex = "[0-9A-Fa-f:.]*:[0-9A-Fa-f:.]*"
+)
+
+var stringFormats = map[string]*stringFormat{
+	"email":    {regex: "[^\\s@<>()]+@[^\\s@<>()]+", parse: parseEmail},
+	"uri":      {regex: "[A-Za-z][A-Za-z0-9+.-]*:\\S+", parse: parseURI},
+	"url":      {regex: "[A-Za-z][A-Za-z0-9+.-]*://\\S+", parse: parseURL},
+	"ipv4":     {regex: ipv4Regex, parse: parseIPv4},
+	"ipv6":     {regex: ipv6Regex, parse: parseIPv6},
+	"ip":       {regex: "(?:" + ipv4Regex + ")|(?:" + ipv6Regex + ")", parse: parseIP},
+	"cidr":     {regex: "[0-9A-Fa-f:.]+/[0-9]{1,3}", parse: parseCIDR},
+	"hostname": {regex: "[A-Za-z0-9](?:[A-Za-z0-9.-]*[A-Za-z0-9.])?", parse: parseHostname},
+	"uuid":     {regex: "(?:urn:uuid:)?\\{?[0-9A-Fa-f-]{36}\\}?", parse: parseUUID},
+	"semver":   {regex: "v?[0-9]+\\.[0-9]+\\.[0-9]+(?:-[0-9A-Za-z.-]+)?(?:\\+[0-9A-Za-z.-]+)?", parse: parseSemver},
+}
+
+//formatValue is a string in one of the built-in formats, it's stored normalized, e.g. IPv6 addresses are compressed
+type formatValue struct {
+	name   string
+	format *stringFormat
+	//schemes accepted by uri and url, empty means any scheme
+	schemes []string
+}
+
+func (v *formatValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
+	log.Println("formatValue [parse]:", value)
+	value = strings.TrimSpace(value)
+	normalized, err := v.format.parse(value)
+	if err == nil && len(v.schemes) > 0 {
+		err = v.checkScheme(normalized)
+	}
+	if err != nil {
+		return nil, newParseErrorAt(meta.position(), fmt.Sprintf("Invalid %s value: %s (%v)", v.name, value, err))
+	}
+	builder.setSimpleValue(normalized)
+	return nil, nil
+}
+
+func (v *formatValue) checkScheme(value string) error {
+	scheme := value[:strings.Index(value, ":")]
+	for _, accepted := range v.schemes {
+		if strings.EqualFold(scheme, accepted) {
+			return nil
+		}
+	}
+	return fmt.Errorf("scheme must be one of %s", strings.Join(v.schemes, ", "))
+}
+
+func (v *formatValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
+	return nil, errors.New("[formatValue] Not supported")
+}
+
+func (v *formatValue) getChild(name string, builder valueBuilder, meta parseMetadata) (valueType, valueBuilder, error) {
+	return nil, nil, errors.New("[formatValue] Not supported")
+}
+
+func (v *formatValue) toRegex(ctx regexBuildContext) string {
+	return v.format.regex
+}
+
+func (v *formatValue) supportsChildren() bool {
+	return false
+}
+
+func (v *formatValue) isSimpleValue() bool {
+	return true
+}
+
+//parseEmail accepts a bare address without display name, the domain is lowercased
+func parseEmail(value string) (string, error) {
+	address, err := mail.ParseAddress(value)
+	if err != nil {
+		return "", errors.New(strings.TrimPrefix(err.Error(), "mail: "))
+	}
+	if address.Name != "" || address.Address != value {
+		return "", errors.New("expected address without display name")
+	}
+	at := strings.LastIndex(address.Address, "@")
+	return address.Address[:at] + "@" + strings.ToLower(address.Address[at+1:]), nil
+}
+
+//parseURI accepts absolute URIs, the scheme is lowercased
+func parseURI(value string) (string, error) {
+	uri, err := parseAbsoluteURI(value)
+	if err != nil {
+		return "", err
+	}
+	return uri.String(), nil
+}
+
+//parseURL accepts URIs with a host, the scheme and the host are lowercased
+func parseURL(value string) (string, error) {
+	uri, err := parseAbsoluteURI(value)
+	if err != nil {
+		return "", err
+	}
+	if uri.Host == "" {
+		return "", errors.New("missing host")
+	}
+	uri.Host = strings.ToLower(uri.Host)
+	return uri.String(), nil
+}
+
+func parseAbsoluteURI(value string) (*url.URL, error) {
+	uri, err := url.Parse(value)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, err
+	}
+	if uri.Scheme == "" {
+		return nil, errors.New("missing scheme")
+	}
+	return uri, nil
+}
+
+func parseIPv4(value string) (string, error) {
+	ip := net.ParseIP(value)
+	if ip == nil || strings.Contains(value, ":") {
+		return "", errors.New("expected four decimal octets")
+	}
+	return ip.String(), nil
+}
+
+//parseIPv6 returns the compressed form, IPv4-mapped addresses keep the IPv6 prefix
+func parseIPv6(value string) (string, error) {
+	ip := net.ParseIP(value)
+	if ip == nil || !strings.Contains(value, ":") {
+		return "", errors.New("expected colon separated hexadecimal groups")
+	}
+	return formatIP(ip), nil
+}
+
+func parseIP(value string) (string, error) {
+	if strings.Contains(value, ":") {
+		return parseIPv6(value)
+	}
+	return parseIPv4(value)
+}
+
+func formatIP(ip net.IP) string {
+	if ip.To4() != nil && len(ip) == net.IPv6len {
+		return "::ffff:" + ip.To4().String()
+	}
+	return ip.String()
+}
+
+//parseCIDR normalizes the address and keeps host bits, e.g. address of an interface with its network
+func parseCIDR(value string) (string, error) {
+	ip, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return "", errors.New("expected address and prefix length, e.g. 10.0.0.0/8")
+	}
+	ones, _ := network.Mask.Size()
+	if strings.Contains(value, ":") {
+		return formatIP(ip) + "/" + strconv.Itoa(ones), nil
+	}
+	return ip.String() + "/" + strconv.Itoa(ones), nil
+}
+
+//parseHostname checks RFC 1123 host names, the name is lowercased and the trailing dot is removed
+func parseHostname(value string) (string, error) {
+	name := strings.TrimSuffix(value, ".")
+	if name == "" || len(name) > 253 {
+		return "", errors.New("expected 1 to 253 characters")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return "", fmt.Errorf("label %q must have 1 to 63 characters", label)
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return "", fmt.Errorf("label %q can't start or end with hyphen", label)
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return "", fmt.Errorf("label %q contains invalid character %q", label, c)
+			}
+		}
+	}
+	return strings.ToLower(name), nil
+}
+
+//parseUUID accepts also braces and urn:uuid: prefix, the result is in lowercase canonical form
+func parseUUID(value string) (string, error) {
+	text := strings.TrimPrefix(strings.ToLower(value), "urn:uuid:")
+	if strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}") {
+		text = text[1 : len(text)-1]
+	}
+	groups := strings.Split(text, "-")
+	sizes := []int{8, 4, 4, 4, 12}
+	if len(groups) != len(sizes) {
+		return "", errors.New("expected 5 groups of hexadecimal digits")
+	}
+	for i, group := range groups {
+		if _, err := hex.DecodeString(group); err != nil || len(group) != sizes[i] {
+			return "", errors.New("expected groups of 8-4-4-4-12 hexadecimal digits")
+		}
+	}
+	return text, nil
+}
+
+//parseSemver checks Semantic Versioning 2.0.0, the v prefix is removed
+func parseSemver(value string) (string, error) {
+	version := strings.TrimPrefix(value, "v")
+	rest := version
+	var build, prerelease string
+	if idx := strings.Index(rest, "+"); idx >= 0 {
+		rest, build = rest[:idx], rest[idx+1:]
+		if err := checkSemverIdentifiers(build, false); err != nil {
+			return "", fmt.Errorf("build metadata: %v", err)
+		}
+	}
+	if idx := strings.Index(rest, "-"); idx >= 0 {
+		rest, prerelease = rest[:idx], rest[idx+1:]
+		if err := checkSemverIdentifiers(prerelease, true); err != nil {
+			return "", fmt.Errorf("pre-release: %v", err)
+		}
+	}
+	numbers := strings.Split(rest, ".")
+	if len(numbers) != 3 {
+		return "", errors.New("expected major.minor.patch")
+	}
+	for _, number := range numbers {
+		if !isNumericIdentifier(number) {
+			return "", fmt.Errorf("invalid version number %q", number)
+		}
+	}
+	return version, nil
+}
+
+//checkSemverIdentifiers checks dot separated identifiers, numeric pre-release identifiers can't have leading zeros
+func checkSemverIdentifiers(identifiers string, prerelease bool) error {
+	for _, identifier := range strings.Split(identifiers, ".") {
+		if identifier == "" {
+			return errors.New("empty identifier")
+		}
+		numeric := true
+		for _, c := range identifier {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return fmt.Errorf("invalid character %q", c)
+			}
+			numeric = numeric && c >= '0' && c <= '9'
+		}
+		if prerelease && numeric && !isNumericIdentifier(identifier) {
+			return fmt.Errorf("leading zero in %q", identifier)
+		}
+	}
+	return nil
+}
+
+func isNumericIdentifier(value string) bool {
+	if value == "" || (len(value) > 1 && value[0] == '0') {
+		return false
+	}
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
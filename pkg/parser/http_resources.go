@@ -0,0 +1,235 @@
+package parser
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//defaultHTTPTimeout limits time of a single download so that parsing doesn't hang on unresponsive servers
+const defaultHTTPTimeout = 30 * time.Second
+
+//HTTPOption configures HTTP resource provider
+type HTTPOption func(c *httpConfig)
+
+type httpConfig struct {
+	client   *http.Client
+	cacheDir string
+	offline  bool
+}
+
+//HTTPClient sets client used to fetch resources, by default a client with 30 seconds timeout is used
+func HTTPClient(client *http.Client) HTTPOption {
+	return func(c *httpConfig) {
+		c.client = client
+	}
+}
+
+//HTTPCacheDir stores fetched resources in given directory. Cached copies are used when the server can't be reached
+//or responds with a server error.
+func HTTPCacheDir(dir string) HTTPOption {
+	return func(c *httpConfig) {
+		c.cacheDir = dir
+	}
+}
+
+//HTTPOffline reads remote resources only from the cache
+func HTTPOffline() HTTPOption {
+	return func(c *httpConfig) {
+		c.offline = true
+	}
+}
+
+//NewHTTPResourceProvider creates resource provider fetching http and https URLs, other resources are provided by
+//the base provider. Relative resources of remote documents are resolved against their URL.
+func NewHTTPResourceProvider(base ResourceProvider, options ...HTTPOption) ResourceProvider {
+	config := &httpConfig{client: &http.Client{Timeout: defaultHTTPTimeout}}
+	for _, option := range options {
+		option(config)
+	}
+	return httpResourceProvider{config: config, base: base}
+}
+
+type httpResourceProvider struct {
+	config *httpConfig
+	//base provides local resources, it's nil for remote documents
+	base ResourceProvider
+	//baseURL of remote document
+	baseURL *url.URL
+}
+
+func (p httpResourceProvider) GetResource(name string) (io.ReadCloser, error) {
+	u, err := p.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return p.base.GetResource(name)
+	}
+	data, err := p.fetch(u)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (p httpResourceProvider) FindResources(pattern string) ([]string, error) {
+	u, err := p.resolve(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return p.base.FindResources(pattern)
+	}
+	if strings.ContainsAny(pattern, "*?[") {
+		return nil, fmt.Errorf("patterns are not supported for remote resources: %s", pattern)
+	}
+	return []string{pattern}, nil
+}
+
+func (p httpResourceProvider) ForResource(name string) ResourceProvider {
+	u, err := p.resolve(name)
+	if err != nil || u == nil {
+		return httpResourceProvider{config: p.config, base: p.base.ForResource(name)}
+	}
+	return httpResourceProvider{config: p.config, baseURL: u}
+}
+
+//resolve returns URL of the remote resource or nil for local ones
+func (p httpResourceProvider) resolve(name string) (*url.URL, error) {
+	if !isURL(name) && p.baseURL == nil {
+		return nil, nil
+	}
+	u, err := url.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+	if p.baseURL != nil {
+		u = p.baseURL.ResolveReference(u)
+	}
+	return u, nil
+}
+
+func (p httpResourceProvider) fetch(u *url.URL) ([]byte, error) {
+	if p.config.offline {
+		return p.readCache(u)
+	}
+	log.Print("fetch resource: ", u)
+	data, err := p.download(u)
+	if err != nil {
+		//the server answered that the resource is gone or inaccessible, the cached copy is outdated then
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.code < http.StatusInternalServerError {
+			return nil, err
+		}
+		if cached, cacheErr := p.readCache(u); cacheErr == nil {
+			log.Print("using cached resource: ", u, ", ", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	if p.config.cacheDir != "" {
+		if err := p.writeCache(u, data); err != nil {
+			log.Print("could not cache resource: ", u, ", ", err)
+		}
+	}
+	return data, nil
+}
+
+func (p httpResourceProvider) download(u *url.URL) ([]byte, error) {
+	resp, err := p.config.client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &httpStatusError{url: u, code: resp.StatusCode, status: resp.Status}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+//httpStatusError is returned when the server responds with other status than 200 OK
+type httpStatusError struct {
+	url    *url.URL
+	code   int
+	status string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("could not fetch %s: %s", e.url, e.status)
+}
+
+func (p httpResourceProvider) cacheFile(u *url.URL) string {
+	sum := sha256.Sum256([]byte(u.String()))
+	return filepath.Join(p.config.cacheDir, hex.EncodeToString(sum[:]))
+}
+
+func (p httpResourceProvider) readCache(u *url.URL) ([]byte, error) {
+	if p.config.cacheDir == "" {
+		return nil, fmt.Errorf("resource %s is not available offline, cache directory is not set", u)
+	}
+	data, err := ioutil.ReadFile(p.cacheFile(u))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("resource %s is not cached", u)
+	}
+	return data, err
+}
+
+//writeCache replaces cached copy atomically so that concurrent readers never see partial content
+func (p httpResourceProvider) writeCache(u *url.URL, data []byte) error {
+	if err := os.MkdirAll(p.config.cacheDir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(p.config.cacheDir, "download-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p.cacheFile(u))
+}
+
+func isURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+//pinnedResources verifies checksum of the pinned resource before it is parsed
+type pinnedResources struct {
+	ResourceProvider
+	name     string
+	checksum string
+}
+
+func (p pinnedResources) GetResource(name string) (io.ReadCloser, error) {
+	file, err := p.ResourceProvider.GetResource(name)
+	if err != nil || name != p.name {
+		return file, err
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if actual := "sha256:" + hex.EncodeToString(sum[:]); !strings.EqualFold(actual, p.checksum) {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, p.checksum, actual)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+//WithMaxImportDepth limits how deep imports, overlay bases and schemas can be nested, 0 means no limit
+func WithMaxImportDepth(depth int) Option {
+	return func(p *Parser) {
+		p.maxImportDepth = depth
+	}
+}
+
+//WithMaxImportedFiles limits total number of files imported by group imports and overlays, 0 means no limit
+func WithMaxImportedFiles(count int) Option {
+	return func(p *Parser) {
+		p.maxImportedFiles = count
+	}
+}
+
+//withImportStack makes schema parser continue the stack of the document that refers to the schema
+func withImportStack(stack *importStack) Option {
+	return func(p *Parser) {
+		p.importStack = stack
+	}
+}
+
+//importStack tracks files that are being parsed to report import cycles and enforce limits
+type importStack struct {
+	files []string
+	//imported is shared by stacks of imports parsed concurrently
+	imported *int64
+	maxDepth int
+	maxFiles int
+}
+
+func (s *importStack) push(fileName string) error {
+	fileName = filepath.Clean(fileName)
+	if fileName != "." {
+		for i, active := range s.files {
+			if active == fileName {
+				return fmt.Errorf("import cycle: %s", strings.Join(append(s.files[i:], fileName), " -> "))
+			}
+		}
+	}
+	if s.maxDepth > 0 && len(s.files) > s.maxDepth {
+		return fmt.Errorf("import depth limit of %d exceeded: %s", s.maxDepth, strings.Join(append(s.files, fileName), " -> "))
+	}
+	s.files = append(s.files, fileName)
+	return nil
+}
+
+func (s *importStack) pop() {
+	s.files = s.files[:len(s.files)-1]
+}
+
+//fork returns copy of the stack for a file parsed concurrently, the count of imported files stays shared
+func (s *importStack) fork() *importStack {
+	return &importStack{files: append([]string{}, s.files...), imported: s.imported, maxDepth: s.maxDepth, maxFiles: s.maxFiles}
+}
+
+//countImport registers another imported file
+func (s *importStack) countImport() error {
+	imported := atomic.AddInt64(s.imported, 1)
+	if s.maxFiles > 0 && imported > int64(s.maxFiles) {
+		return fmt.Errorf("imported files limit of %d exceeded", s.maxFiles)
+	}
+	return nil
+}
+
+//importedFile is a file matched by group import
+type importedFile struct {
+	path       string
+	fileName   string
+	schemaNode valueType
+	builder    valueBuilder
+	pos        Position
+}
+
+//parseImports parses files matched by group import. Files feeding separate empty nodes are parsed concurrently into
+//isolated subtrees which are merged in the order of paths, so the result doesn't depend on which file finishes first.
+func (p *Parser) parseImports(document *documentContext, files []*importedFile, resources ResourceProvider) error {
+	if !isolatedImports(files) {
+		for _, file := range files {
+			p.dependencies.add(Dependency{Kind: DependencyImport, File: file.fileName, From: document.fileName, Path: file.builder.getPath()})
+			if err := p.parseImport(document, file, file.builder, resources); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	type result struct {
+		parser   *Parser
+		document *documentContext
+		value    Node
+		err      error
+	}
+	results := make([]*result, len(files))
+	workers := make(chan struct{}, runtime.GOMAXPROCS(0))
+	var wg sync.WaitGroup
+	for i, file := range files {
+		res := &result{parser: p.fork(), document: document.fork(), value: Node{}}
+		results[i] = res
+		wg.Add(1)
+		go func(file *importedFile) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			builder := &itemInMapValueBuilder{parent: res.value, fieldName: "value", path: file.builder.getPath()}
+			res.err = res.parser.parseImport(res.document, file, builder, resources)
+		}(file)
+	}
+	wg.Wait()
+
+	for i, file := range files {
+		res := results[i]
+		p.dependencies.add(Dependency{Kind: DependencyImport, File: file.fileName, From: document.fileName, Path: file.builder.getPath()})
+		p.dependencies.merge(res.parser.dependencies)
+		if res.err != nil {
+			return res.err
+		}
+		if value, ok := res.value["value"]; ok {
+			file.builder.setSimpleValue(value)
+		}
+		p.sourceMap.merge(res.parser.sourceMap)
+		document.session.merge(res.document.session)
+	}
+	return nil
+}
+
+//parseImport parses single imported file into given builder, the file is closed as soon as it's parsed
+func (p *Parser) parseImport(document *documentContext, file *importedFile, builder valueBuilder, resources ResourceProvider) error {
+	reader, err := resources.GetResource(file.path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if _, ok := file.schemaNode.(*stringValue); ok {
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		builder.setSimpleValue(string(data))
+		return nil
+	}
+	if err := document.imports.push(file.fileName); err != nil {
+		return newParseErrorAt(file.pos, err.Error())
+	}
+	defer document.imports.pop()
+	return p.parseDocument(reader, resources.ForResource(file.path), builder, &dadlSchemaImpl{root: file.schemaNode}, document.child(file.fileName))
+}
+
+//isolatedImports checks if every file is imported into its own node that is still empty
+func isolatedImports(files []*importedFile) bool {
+	if len(files) < 2 {
+		return false
+	}
+	paths := map[string]bool{}
+	for _, file := range files {
+		switch file.builder.(type) {
+		case *itemInMapValueBuilder, *itemInListValueBuilder:
+		default:
+			return false
+		}
+		path := file.builder.getPath()
+		if paths[path] || file.builder.getSimpleValue() != nil {
+			return false
+		}
+		paths[path] = true
+	}
+	return true
+}
+
+//fork returns parser that collects source positions and dependencies of concurrently parsed file separately
+func (p *Parser) fork() *Parser {
+	forked := *p
+	if p.sourceMap != nil {
+		forked.sourceMap = SourceMap{}
+	}
+	if p.dependencies != nil {
+		forked.dependencies = &Dependencies{}
+	}
+	return &forked
+}
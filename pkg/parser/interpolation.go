@@ -0,0 +1,261 @@
+package parser
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dadlang/dadl/pkg/export"
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+var referenceRe = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+//deferredValue is a value containing references that can be parsed only after the whole tree is built
+type deferredValue struct {
+	path      string
+	valueType valueType
+	value     string
+	meta      parseMetadata
+}
+
+//parseValue parses value of a node. Values containing ${path} references are parsed after the whole tree,
+//including imports, is built. $${ is an escape of literal ${, values with escapes only are parsed right away.
+func parseValue(valueType valueType, builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
+	if meta.session != nil && strings.Contains(value, "${") {
+		if literal, ok := unescapeReferences(value); ok {
+			return valueType.parse(builder, literal, meta)
+		}
+		log.Println("parseValue [deferred]:", builder.getPath(), value)
+		meta.session.deferred = append(meta.session.deferred, &deferredValue{
+			path:      builder.getPath(),
+			valueType: valueType,
+			value:     value,
+			meta:      meta,
+		})
+		return nil, nil
+	}
+	return valueType.parse(builder, value, meta)
+}
+
+//unescapeReferences replaces $${ escapes with ${, it returns false when the value contains a reference
+func unescapeReferences(value string) (string, bool) {
+	var sb strings.Builder
+	last := 0
+	for _, match := range referenceRe.FindAllStringSubmatchIndex(value, -1) {
+		if match[2] >= 0 {
+			return "", false
+		}
+		sb.WriteString(value[last:match[0]])
+		sb.WriteString("${")
+		last = match[1]
+	}
+	sb.WriteString(value[last:])
+	return sb.String(), true
+}
+
+//inheritDeferred takes over values deferred while parsing the base of an overlay,
+//values replaced by the overlay are dropped
+func (s *parseSession) inheritDeferred(base *parseSession, overrides []Override) {
+	overlayPaths := map[string]bool{}
+	for _, item := range s.deferred {
+		overlayPaths[item.path] = true
+	}
+	inherited := []*deferredValue{}
+	for _, item := range base.deferred {
+		if !overlayPaths[item.path] && !isOverridden(item.path, overrides) {
+			inherited = append(inherited, item)
+		}
+	}
+	s.deferred = append(inherited, s.deferred...)
+}
+
+//shiftAppended moves paths of values parsed from an overlay into lists appended to the base list, their items
+//follow items of the base list
+func (s *parseSession) shiftAppended(overrides []Override) {
+	for _, override := range overrides {
+		base, ok := override.OldValue.([]interface{})
+		if override.Action != MergeAppended || !ok {
+			continue
+		}
+		for _, item := range s.deferred {
+			item.path = shiftListIndex(item.path, override.Path, len(base))
+		}
+		for _, ref := range s.references {
+			ref.path = shiftListIndex(ref.path, override.Path, len(base))
+		}
+		for _, check := range s.checks {
+			check.path = shiftListIndex(check.path, override.Path, len(base))
+		}
+	}
+}
+
+//shiftListIndex adds offset to index of the list item in the path, e.g. hosts[1].name is hosts[3].name for list hosts
+//and offset 2
+func shiftListIndex(path string, listPath string, offset int) string {
+	if !strings.HasPrefix(path, listPath+"[") {
+		return path
+	}
+	rest := path[len(listPath)+1:]
+	end := strings.Index(rest, "]")
+	if end < 0 {
+		return path
+	}
+	idx, err := strconv.Atoi(rest[:end])
+	if err != nil {
+		return path
+	}
+	return listPath + "[" + strconv.Itoa(idx+offset) + rest[end:]
+}
+
+func isOverridden(path string, overrides []Override) bool {
+	for _, override := range overrides {
+		if override.Action != MergeAppended && isSameOrDescendant(path, override.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSameOrDescendant(path string, ancestor string) bool {
+	return path == ancestor || ancestor == "" ||
+		strings.HasPrefix(path, ancestor+".") || strings.HasPrefix(path, ancestor+"[")
+}
+
+const (
+	referenceResolving = iota + 1
+	referenceResolved
+)
+
+type referenceResolver struct {
+	root  Node
+	items []*deferredValue
+	state map[*deferredValue]int
+}
+
+//resolveReferences parses deferred values once the whole tree is available
+func (s *parseSession) resolveReferences(root Node) error {
+	r := &referenceResolver{root: root, items: s.deferred, state: map[*deferredValue]int{}}
+	for _, item := range s.deferred {
+		if err := r.resolve(item, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *referenceResolver) resolve(item *deferredValue, chain []string) error {
+	switch r.state[item] {
+	case referenceResolved:
+		return nil
+	case referenceResolving:
+		return newParseErrorAt(item.meta.position(), "reference cycle: "+strings.Join(append(chain, item.path), " -> "))
+	}
+	r.state[item] = referenceResolving
+	chain = append(chain, item.path)
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range referenceRe.FindAllStringSubmatchIndex(item.value, -1) {
+		sb.WriteString(item.value[last:match[0]])
+		last = match[1]
+		if match[2] < 0 {
+			sb.WriteString("${")
+			continue
+		}
+		value, err := r.lookup(strings.TrimSpace(item.value[match[2]:match[3]]), item, chain)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(value)
+	}
+	sb.WriteString(item.value[last:])
+
+	builder, err := builderAt(r.root, item.path)
+	if err != nil {
+		return newParseErrorAt(item.meta.position(), err.Error())
+	}
+	_, err = item.valueType.parse(builder, sb.String(), item.meta)
+	if err != nil {
+		if parseErr, ok := err.(ParseError); ok {
+			return newParseErrorAt(item.meta.position(), fmt.Sprintf("invalid value after interpolation %q: %s", sb.String(), parseErr.GetReason()))
+		}
+		return newParseErrorAt(item.meta.position(), fmt.Sprintf("invalid value after interpolation %q: %v", sb.String(), err))
+	}
+	r.state[item] = referenceResolved
+	return nil
+}
+
+func (r *referenceResolver) lookup(reference string, item *deferredValue, chain []string) (string, error) {
+	if strings.HasPrefix(reference, "env:") {
+		return "", newParseErrorAt(item.meta.position(), "environment variable substitution is disabled: ${"+reference+"}")
+	}
+	for _, dependency := range r.items {
+		if isSameOrDescendant(reference, dependency.path) && dependency.path != "" {
+			if err := r.resolve(dependency, chain); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	matches, err := query.Select(r.root, reference)
+	if err != nil {
+		return "", newParseErrorAt(item.meta.position(), err.Error())
+	}
+	if len(matches) != 1 {
+		if len(matches) == 0 {
+			return "", newParseErrorAt(item.meta.position(), "reference to missing path: "+reference)
+		}
+		return "", newParseErrorAt(item.meta.position(), "reference matches more than one value: "+reference)
+	}
+	switch value := matches[0].Value.(type) {
+	case string:
+		return value, nil
+	case int:
+		return strconv.Itoa(value), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case *big.Int:
+		return value.String(), nil
+	case time.Time, time.Duration:
+		//formatted the same way as exported values, so that they can be parsed by date, time and duration types
+		return export.Value(value).(string), nil
+	case map[string]interface{}, []interface{}, nil:
+		return "", newParseErrorAt(item.meta.position(), "reference to non scalar value: "+reference)
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+//builderAt returns builder of the value at given path of the tree
+func builderAt(root Node, path string) (valueBuilder, error) {
+	parentPath, ok := query.Parent(path)
+	if !ok {
+		return &dynamicMapOrListValueBuilder{value: root}, nil
+	}
+	parent, err := query.Get(root, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	segment := strings.TrimPrefix(path[len(parentPath):], ".")
+	if strings.HasPrefix(segment, "['") {
+		segment = query.Base(path)
+	} else if strings.HasPrefix(segment, "[") {
+		idx, err := strconv.Atoi(segment[1 : len(segment)-1])
+		if err != nil {
+			return nil, err
+		}
+		if list, ok := parent.([]interface{}); ok && idx < len(list) {
+			return &itemInListValueBuilder{parent: list, idx: idx, path: path}, nil
+		}
+		return nil, fmt.Errorf("%s is not a list", parentPath)
+	}
+	if asMap, ok := parent.(map[string]interface{}); ok {
+		return &itemInMapValueBuilder{parent: asMap, fieldName: segment, path: path}, nil
+	}
+	return nil, fmt.Errorf("%s is not a map", parentPath)
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+//MergeOptions configures how overlays are merged on top of a base document
+type MergeOptions struct {
+	//AppendLists appends overlay list items to base lists instead of replacing them
+	AppendLists bool
+}
+
+//MergeAction describes how overlay value was applied to the base document
+type MergeAction string
+
+//Merge actions
+const (
+	MergeAdded    MergeAction = "add"
+	MergeReplaced MergeAction = "override"
+	MergeAppended MergeAction = "append"
+)
+
+//Override describes a single value of the base document changed by an overlay
+type Override struct {
+	Action   MergeAction `json:"action"`
+	Path     string      `json:"path"`
+	OldValue interface{} `json:"old,omitempty"`
+	NewValue interface{} `json:"new"`
+	Source   string      `json:"source,omitempty"`
+}
+
+func (o Override) String() string {
+	path := o.Path
+	if path == "" {
+		path = "."
+	}
+	var result string
+	if o.Action == MergeAdded {
+		result = fmt.Sprintf("%s %s: %v", o.Action, path, o.NewValue)
+	} else {
+		result = fmt.Sprintf("%s %s: %v -> %v", o.Action, path, o.OldValue, o.NewValue)
+	}
+	if o.Source != "" {
+		result += " (" + o.Source + ")"
+	}
+	return result
+}
+
+//MergeReport collects overrides applied while merging overlays
+type MergeReport []Override
+
+//Merge deep merges overlay on top of base. Maps and structs are merged by key, lists and simple values
+//are replaced. Neither base nor overlay is modified.
+func Merge(base Node, overlay Node, options MergeOptions) (Node, []Override) {
+	overrides := []Override{}
+	return mergeMaps("", base, overlay, options, &overrides), overrides
+}
+
+func mergeMaps(path string, base map[string]interface{}, overlay map[string]interface{}, options MergeOptions, overrides *[]Override) map[string]interface{} {
+	result := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		result[k] = v
+	}
+	for _, key := range query.SortedKeys(overlay) {
+		result[key] = mergeValues(query.Key(path, key), base[key], overlay[key], options, overrides)
+	}
+	return result
+}
+
+func mergeValues(path string, base interface{}, overlay interface{}, options MergeOptions, overrides *[]Override) interface{} {
+	if base == nil {
+		*overrides = append(*overrides, Override{Action: MergeAdded, Path: path, NewValue: overlay})
+		return overlay
+	}
+	switch base := base.(type) {
+	case map[string]interface{}:
+		if overlay, ok := overlay.(map[string]interface{}); ok {
+			return mergeMaps(path, base, overlay, options, overrides)
+		}
+	case []interface{}:
+		if overlay, ok := overlay.([]interface{}); ok && options.AppendLists {
+			*overrides = append(*overrides, Override{Action: MergeAppended, Path: path, OldValue: base, NewValue: overlay})
+			result := make([]interface{}, 0, len(base)+len(overlay))
+			return append(append(result, base...), overlay...)
+		}
+	}
+	if !reflect.DeepEqual(base, overlay) {
+		*overrides = append(*overrides, Override{Action: MergeReplaced, Path: path, OldValue: base, NewValue: overlay})
+	}
+	return overlay
+}
@@ -0,0 +1,287 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//Outline describes schema of the lines of the main document, it's indexed by line numbers starting with 1
+type Outline map[int]*LineSchema
+
+//LineSchema describes schema of the node defined in a line of the document
+type LineSchema struct {
+	//Path of the node, it's empty when the line could not be parsed
+	Path string
+	//Type describes type of the node with its constraints, e.g. int 0..65535
+	Type string
+	//Doc is a # comment of the node in the schema
+	Doc string
+	//Keys are names of the children accepted by the parent of the node
+	Keys []string
+	//Values are values accepted by the node, e.g. values of an enum
+	Values []string
+}
+
+//WithOutline records schema of every line of the main document in given outline. Lines are recorded
+//before they are parsed, so the outline describes also the line that failed to parse.
+func WithOutline(outline Outline) Option {
+	return func(p *Parser) {
+		p.outline = outline
+	}
+}
+
+//LastLine returns number of the last line reached by the parser
+func (o Outline) LastLine() int {
+	last := 0
+	for lineNo := range o {
+		if lineNo > last {
+			last = lineNo
+		}
+	}
+	return last
+}
+
+func (o Outline) touch(lineNo int) {
+	if o != nil {
+		o[lineNo] = &LineSchema{}
+	}
+}
+
+//recordLine describes a line containing child of the parent node
+func (o Outline) recordLine(lineNo int, parent *nodeInfo, line string) {
+	if o == nil || parent == nil || parent.valueType == nil {
+		return
+	}
+	key := line
+	if fields := strings.Fields(line); len(fields) > 0 {
+		key = removeQuotes(fields[0])
+	}
+	node, doc := childSchema(parent.valueType, parent.valueMeta, key)
+	o[lineNo] = &LineSchema{Type: describeType(node), Doc: doc, Keys: childKeys(parent.valueType, parent.valueMeta), Values: nodeValues(node)}
+}
+
+//recordGroup describes a line with [group] definition
+func (o Outline) recordGroup(lineNo int, schema DadlSchema, treePath string, path string) {
+	if o == nil {
+		return
+	}
+	var node, parent valueType = schema.getRoot(), nil
+	var doc string
+	for _, key := range strings.Split(treePath, ".") {
+		parent = node
+		node, doc = childSchema(parent, nil, key)
+		if node == nil {
+			return
+		}
+	}
+	o[lineNo] = &LineSchema{Path: path, Type: describeType(node), Doc: doc, Keys: childKeys(parent, nil), Values: nodeValues(node)}
+}
+
+func (o Outline) recordPath(lineNo int, path string) {
+	if line, ok := o[lineNo]; ok {
+		line.Path = path
+	}
+}
+
+//childSchema returns type and doc comment of the child of the node, lists ignore the key
+func childSchema(node valueType, meta *valueMeta, key string) (valueType, string) {
+	switch node := node.(type) {
+	case *structValue:
+		return node.children[key], node.docs[key]
+	case *mapValue:
+		return node.valueType, ""
+	case *listValue:
+		return node.childType, ""
+	case *complexValue:
+		return childSchema(node.structValue, meta, key)
+	case *delegatedValue:
+		return childSchema(node.target, meta, key)
+	case *formulaValue:
+		if item := findStructItem(node.formula); item != nil {
+			return childSchema(item.valueType, meta, key)
+		}
+	case *oneofValue:
+		if lastMatch, ok := meta.getMeta("lastMatch").(int); ok {
+			return childSchema(node.options[lastMatch].ValueType, meta, key)
+		}
+	}
+	return nil, ""
+}
+
+//childKeys returns sorted names of children accepted by the node
+func childKeys(node valueType, meta *valueMeta) []string {
+	switch node := node.(type) {
+	case *structValue:
+		keys := make([]string, 0, len(node.children))
+		for key := range node.children {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	case *complexValue:
+		return childKeys(node.structValue, meta)
+	case *delegatedValue:
+		return childKeys(node.target, meta)
+	case *formulaValue:
+		if item := findStructItem(node.formula); item != nil {
+			return childKeys(item.valueType, meta)
+		}
+	case *oneofValue:
+		if lastMatch, ok := meta.getMeta("lastMatch").(int); ok {
+			return childKeys(node.options[lastMatch].ValueType, meta)
+		}
+	}
+	return nil
+}
+
+//nodeValues returns sorted values accepted by the node
+func nodeValues(node valueType) []string {
+	switch node := node.(type) {
+	case *enumValue:
+		values := make([]string, 0, len(node.values))
+		for value := range node.values {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		return values
+	case *boolValue:
+		return []string{"false", "true"}
+	case *delegatedValue:
+		return nodeValues(node.target)
+	}
+	return nil
+}
+
+func findStructItem(items []formulaItem) *formulaItem {
+	for i, item := range items {
+		if item.asStructType {
+			return &items[i]
+		}
+		if found := findStructItem(item.children); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+//describeType describes the type and its constraints with the syntax of schema files
+func describeType(node valueType) string {
+	if node == nil {
+		return ""
+	}
+	return typeDescriber{visited: map[valueType]bool{}}.describe(node)
+}
+
+type typeDescriber struct {
+	visited map[valueType]bool
+}
+
+func (d typeDescriber) describe(node valueType) string {
+	if d.visited[node] {
+		return "..."
+	}
+	d.visited[node] = true
+	defer delete(d.visited, node)
+
+	switch node := node.(type) {
+	case *stringValue:
+		res := "string"
+		if node.regex != "" {
+			res += " `" + node.regex + "`"
+		}
+		if node.minLen > 0 {
+			res += fmt.Sprintf(" minLen %d", node.minLen)
+		}
+		if node.maxLen > 0 {
+			res += fmt.Sprintf(" maxLen %d", node.maxLen)
+		}
+		if node.noTrim {
+			res += " notrim"
+		}
+		return res
+	case *intValue:
+		kind := node.kind
+		if kind == "" {
+			kind = "int"
+		}
+		if _, sized := intKindRanges[kind]; !sized && (node.min != nil || node.max != nil) {
+			return kind + " " + node.rangeString()
+		}
+		return kind
+	case *numberValue:
+		res := "number"
+		if node.big {
+			res += " big"
+		}
+		if node.min != nil || node.max != nil || node.minExclusive || node.maxExclusive {
+			res += " " + node.rangeString()
+		}
+		return res
+	case *boolValue:
+		return "bool"
+	case *timeValue:
+		if node.min != nil || node.max != nil {
+			return node.kind + " " + node.rangeString()
+		}
+		return node.kind
+	case *durationValue:
+		if node.min != nil || node.max != nil {
+			return "duration " + node.rangeString()
+		}
+		return "duration"
+	case *formatValue:
+		if len(node.schemes) > 0 {
+			return node.name + " " + strings.Join(node.schemes, "|")
+		}
+		return node.name
+	case *binaryValue:
+		return "binary"
+	case *constantValue:
+		return "'" + node.value + "'"
+	case *enumValue:
+		return "enum " + strings.Join(nodeValues(node), " ")
+	case *formulaValue:
+		return "formula " + d.describeItems(node.formula)
+	case *sequenceValue:
+		return "sequence[" + d.describe(node.itemType) + "]"
+	case *listValue:
+		return "list[" + d.describe(node.childType) + "]"
+	case *mapValue:
+		return "map[" + d.describe(node.keyType) + "]" + d.describe(node.valueType)
+	case *structValue:
+		return "struct"
+	case *oneofValue:
+		names := make([]string, len(node.options))
+		for i, option := range node.options {
+			names[i] = option.Name
+		}
+		return "oneof[" + strings.Join(names, "|") + "]"
+	case *complexValue:
+		return "complex[" + d.describe(node.textValue) + "]" + d.describe(node.structValue)
+	case *refValue:
+		return "ref[" + node.target.String() + "]"
+	case *delegatedValue:
+		return d.describe(node.target)
+	}
+	return fmt.Sprintf("%T", node)
+}
+
+func (d typeDescriber) describeItems(items []formulaItem) string {
+	parts := []string{}
+	for _, item := range items {
+		switch {
+		case item.composite:
+			parts = append(parts, "["+d.describeItems(item.children)+"]")
+		case item.name != "":
+			parts = append(parts, "<"+item.name+" "+d.describe(item.valueType)+">")
+		default:
+			if value, ok := item.valueType.(*stringValue); ok {
+				parts = append(parts, "`"+value.regex+"`")
+			} else {
+				parts = append(parts, d.describe(item.valueType))
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
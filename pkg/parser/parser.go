@@ -5,17 +5,53 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
+	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"unicode"
 )
 
 //NewParser - creates new Parser instance
-func NewParser() Parser {
-	return Parser{}
+func NewParser(options ...Option) Parser {
+	p := Parser{}
+	for _, option := range options {
+		option(&p)
+	}
+	return p
+}
+
+//Option configures Parser
+type Option func(p *Parser)
+
+//WithFileName sets name of the parsed file, it's used to describe positions of parsed nodes
+func WithFileName(fileName string) Option {
+	return func(p *Parser) {
+		p.fileName = fileName
+	}
+}
+
+//WithSourceMap records position of every parsed node in given source map
+func WithSourceMap(sourceMap SourceMap) Option {
+	return func(p *Parser) {
+		p.sourceMap = sourceMap
+	}
+}
+
+//WithMergeOptions configures how documents using @overlay directive are merged with their base
+func WithMergeOptions(options MergeOptions) Option {
+	return func(p *Parser) {
+		p.mergeOptions = options
+	}
+}
+
+//WithMergeReport collects values overridden by documents using @overlay directive
+func WithMergeReport(report *MergeReport) Option {
+	return func(p *Parser) {
+		p.mergeReport = report
+	}
 }
 
 //ParseError describes a parsing error
@@ -24,6 +60,7 @@ type ParseError interface {
 	GetLine() int
 	GetColumn() int
 	GetReason() string
+	GetFile() string
 }
 
 //DefaultParseError default ParseError
@@ -31,13 +68,17 @@ type defaultParseError struct {
 	line   int
 	column int
 	reason string
+	file   string
 }
 
-func newParseError(line int, col int, reason string) error {
-	return defaultParseError{line, col, reason}
+func newParseErrorAt(pos Position, reason string) error {
+	return defaultParseError{line: pos.Line, column: pos.Column, reason: reason, file: pos.File}
 }
 
 func (e defaultParseError) Error() string {
+	if e.file != "" {
+		return fmt.Sprintf("Parse error [file: %v, line: %v, col: %v]: %v", e.file, e.line, e.column, e.reason)
+	}
 	return fmt.Sprintf("Parse error [line: %v, col: %v]: %v", e.line, e.column, e.reason)
 }
 
@@ -53,6 +94,11 @@ func (e defaultParseError) GetReason() string {
 	return e.reason
 }
 
+//GetFile returns name of the file the error was found in, it's empty when file name is not known
+func (e defaultParseError) GetFile() string {
+	return e.file
+}
+
 type nodeInfo struct {
 	valueType valueType
 	builder   valueBuilder
@@ -65,6 +111,57 @@ type parseContext struct {
 	parentNodeInfo *nodeInfo
 	lastNodeInfo   *nodeInfo
 	lineNo         int
+	document       *documentContext
+}
+
+//documentContext holds state shared by all contexts of a single parsed file
+type documentContext struct {
+	fileName       string
+	session        *parseSession
+	overlayBase    Node
+	overlaySources SourceMap
+	overlaySession *parseSession
+	outline        Outline
+	imports        *importStack
+	schemas        *SchemaCache
+}
+
+func (d *documentContext) child(fileName string) *documentContext {
+	return &documentContext{fileName: fileName, session: d.session, imports: d.imports, schemas: d.schemas}
+}
+
+//fork returns context with its own session and import stack for a file parsed concurrently
+func (d *documentContext) fork() *documentContext {
+	forked := &documentContext{fileName: d.fileName, imports: d.imports.fork(), schemas: d.schemas}
+	if d.session != nil {
+		forked.session = &parseSession{}
+	}
+	return forked
+}
+
+//parseSession holds state shared by the main document and all documents imported by it
+type parseSession struct {
+	deferred   []*deferredValue
+	references []*reference
+	checks     []*valueCheck
+}
+
+//merge appends values deferred and references found in a file parsed with separate session
+func (s *parseSession) merge(other *parseSession) {
+	if s == nil || other == nil {
+		return
+	}
+	s.deferred = append(s.deferred, other.deferred...)
+	s.references = append(s.references, other.references...)
+	s.checks = append(s.checks, other.checks...)
+}
+
+func (ctx *parseContext) metadata(colNo int) parseMetadata {
+	return parseMetadata{fileName: ctx.document.fileName, lineNo: ctx.lineNo, colNo: colNo, session: ctx.document.session}
+}
+
+func (ctx *parseContext) position(colNo int) Position {
+	return Position{File: ctx.document.fileName, Line: ctx.lineNo, Column: colNo}
 }
 
 var groupRe = regexp.MustCompile(`^\[(?P<treePath>[a-zA-Z0-9-_.$]*)\s*(?:<\s*(?P<importPath>.+))?\]$`)
@@ -74,7 +171,24 @@ func (p *Parser) Parse(reader io.Reader, resources ResourceProvider) (Node, erro
 	root := Node{}
 	rootBuilder := &dynamicMapOrListValueBuilder{value: root}
 
-	err := p.ParseWithBuilderAndSchema(reader, resources, rootBuilder, nil)
+	imports := p.importStack
+	if imports == nil {
+		imports = p.newImportStack()
+	}
+	session := &parseSession{}
+	err := p.parseDocument(reader, resources, rootBuilder, nil, &documentContext{fileName: p.fileName, session: session, outline: p.outline, imports: imports, schemas: p.newSchemaCache()})
+	if err != nil {
+		return nil, err
+	}
+	err = session.resolveReferences(root)
+	if err != nil {
+		return nil, err
+	}
+	err = session.checkValues(root)
+	if err != nil {
+		return nil, err
+	}
+	err = session.checkReferences(root, p.resolveRefs)
 	if err != nil {
 		return nil, err
 	}
@@ -82,8 +196,29 @@ func (p *Parser) Parse(reader io.Reader, resources ResourceProvider) (Node, erro
 }
 
 func (p *Parser) ParseWithBuilderAndSchema(reader io.Reader, resources ResourceProvider, builder valueBuilder, schema DadlSchema) error {
+	return p.parseDocument(reader, resources, builder, schema, &documentContext{fileName: p.fileName, imports: p.newImportStack(), schemas: p.newSchemaCache()})
+}
+
+//newSchemaCache returns cache shared by the document and its imports
+func (p *Parser) newSchemaCache() *SchemaCache {
+	cache := p.schemaCache
+	if cache == nil {
+		cache = NewSchemaCache()
+	}
+	for _, schema := range p.schemas {
+		cache.Add(schema)
+	}
+	return cache
+}
+
+//newImportStack creates stack for the main document
+func (p *Parser) newImportStack() *importStack {
+	return &importStack{files: []string{filepath.Clean(p.fileName)}, imported: new(int64), maxDepth: p.maxImportDepth, maxFiles: p.maxImportedFiles}
+}
+
+func (p *Parser) parseDocument(reader io.Reader, resources ResourceProvider, builder valueBuilder, schema DadlSchema, document *documentContext) error {
 
-	ctx := &parseContext{schema: schema}
+	ctx := &parseContext{schema: schema, document: document}
 
 	if schema != nil {
 		ctx.parentNodeInfo = &nodeInfo{
@@ -106,16 +241,31 @@ func (p *Parser) ParseWithBuilderAndSchema(reader io.Reader, resources ResourceP
 			log.Fatal(err)
 		}
 
-		line := strings.TrimRight(scanner.Text(), "\t \n")
+		rawLine := strings.TrimRight(scanner.Text(), "\t \n")
+		line := rawLine
 		ctx.lineNo = lineNo
 
+		if !strings.HasPrefix(line, "#") {
+			line, err = p.substituteEnv(line, ctx.position(0), false)
+			if err != nil {
+				return err
+			}
+		}
+
 		indentWeight := calcIndentWeight(line)
+		if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#") {
+			ctx.document.outline.touch(lineNo)
+		}
 
 		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
 			log.Println("process group:", line)
+			pos := ctx.position(0)
+			if err := p.beginLine(builder, pos); err != nil {
+				return err
+			}
 			ctx, err = p.processGroup(line, ctx, builder, resources)
 			if err != nil {
-				return err
+				return resourceError(err, pos)
 			}
 			ctxByIndent = make([]*parseContext, 100)
 			ctxByIndent[0] = ctx
@@ -123,9 +273,9 @@ func (p *Parser) ParseWithBuilderAndSchema(reader io.Reader, resources ResourceP
 			if strings.HasPrefix(line, "#") {
 				log.Println("skip comment:", line)
 			} else if strings.HasPrefix(line, "@") {
-				err := p.parseMagic(ctx, builder, line, parseMetadata{lineNo: ctx.lineNo, colNo: 0}, resources)
+				err := p.parseMagic(ctx, builder, line, ctx.metadata(0), resources)
 				if err != nil {
-					return err
+					return resourceError(err, ctx.position(0))
 				}
 			} else {
 				if indentWeight > ctx.indentWeight {
@@ -133,7 +283,8 @@ func (p *Parser) ParseWithBuilderAndSchema(reader io.Reader, resources ResourceP
 					ctx = &parseContext{
 						schema:         ctx.schema,
 						indentWeight:   indentWeight,
-						parentNodeInfo: nextParentInfo}
+						parentNodeInfo: nextParentInfo,
+						document:       ctx.document}
 					ctxByIndent[indentWeight] = ctx
 				} else if indentWeight < ctx.indentWeight {
 
@@ -145,15 +296,51 @@ func (p *Parser) ParseWithBuilderAndSchema(reader io.Reader, resources ResourceP
 					}
 				}
 				ctx.lineNo = lineNo
+				if err := p.beginLine(builder, ctx.position(indentWeight)); err != nil {
+					return err
+				}
 
-				ctx.lastNodeInfo, err = ctx.parentNodeInfo.valueType.parseChild(ctx.parentNodeInfo.builder, line, ctx.parentNodeInfo.valueMeta, parseMetadata{lineNo: ctx.lineNo, colNo: 0})
+				if ctx.parentNodeInfo == nil {
+					return errMissingSchema(ctx.position(0))
+				}
+				if ctx.document.session != nil && !ctx.parentNodeInfo.valueType.isSimpleValue() {
+					//the value is interpolated once the tree is built, lines of multiline text are kept as they are
+					line, err = p.substituteEnv(rawLine, ctx.position(0), true)
+					if err != nil {
+						return err
+					}
+				}
+				ctx.document.outline.recordLine(lineNo, ctx.parentNodeInfo, line)
+				ctx.lastNodeInfo, err = ctx.parentNodeInfo.valueType.parseChild(ctx.parentNodeInfo.builder, line, ctx.parentNodeInfo.valueMeta, ctx.metadata(0))
 				if err != nil {
 					return err
 				}
+				if ctx.lastNodeInfo.valueMeta != nil && ctx.lastNodeInfo.valueMeta.getMeta("lineIndent") == nil {
+					//indentation of the line that starts the node, text kept as written is relative to it
+					ctx.lastNodeInfo.valueMeta.setMeta("lineIndent", indentWeight)
+				}
+				ctx.document.outline.recordPath(lineNo, ctx.lastNodeInfo.builder.getPath())
+				p.sourceMap.record(ctx.lastNodeInfo.builder.getPath(), ctx.position(indentWeight))
 			}
 		}
 		lineNo++
 	}
+	if ctx.document.overlayBase != nil {
+		p.applyOverlay(ctx.document, builder)
+	}
+	return nil
+}
+
+//errMissingSchema reports a node found before the @schema line
+func errMissingSchema(pos Position) error {
+	return newParseErrorAt(pos, "missing @schema, it has to be declared before the first node")
+}
+
+//beginLine tells streaming builders which line is parsed, it returns error of the event handler
+func (p *Parser) beginLine(builder valueBuilder, pos Position) error {
+	if observer, ok := builder.(lineObserver); ok {
+		return observer.beginLine(pos)
+	}
 	return nil
 }
 
@@ -169,7 +356,7 @@ func (p *Parser) processGroup(line string, ctx *parseContext, rootBuilder valueB
 		treePath := result["treePath"]
 		importPath := result["importPath"]
 		if ctx.schema == nil {
-			return nil, errors.New("missing schema info")
+			return nil, errMissingSchema(ctx.position(0))
 		}
 
 		var valueMeta *valueMeta
@@ -178,6 +365,7 @@ func (p *Parser) processGroup(line string, ctx *parseContext, rootBuilder valueB
 		var err error
 
 		if importPath != "" {
+			p.dependencies.addPattern(resourceFileName(ctx.document.fileName, importPath))
 			paths, err := resources.FindResources(importPath)
 			if err != nil {
 				return nil, err
@@ -187,14 +375,13 @@ func (p *Parser) processGroup(line string, ctx *parseContext, rootBuilder valueB
 			if len(paths) == 0 {
 				return nil, fmt.Errorf("no file matches given path: %s", importPath)
 			}
+			sort.Strings(paths)
 
-			for _, path := range paths {
-				file, err := resources.GetResource(path)
-				if err != nil {
-					return nil, err
+			files := make([]*importedFile, len(paths))
+			for i, path := range paths {
+				if err := ctx.document.imports.countImport(); err != nil {
+					return nil, newParseErrorAt(ctx.position(0), err.Error())
 				}
-				defer file.Close()
-
 				_, fileName := filepath.Split(path)
 
 				targetPath := treePath
@@ -202,40 +389,54 @@ func (p *Parser) processGroup(line string, ctx *parseContext, rootBuilder valueB
 					targetPath = strings.TrimRight(treePath, "_") + strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
 				}
 
-				schemaNode, valueBuilder, err = ctx.schema.getNode(targetPath, rootBuilder, parseMetadata{lineNo: ctx.lineNo, colNo: 0})
+				schemaNode, valueBuilder, err = ctx.schema.getNode(targetPath, rootBuilder, ctx.metadata(0))
 				if err != nil {
 					return nil, err
 				}
-
-				if _, ok := schemaNode.(*stringValue); ok {
-					data, err := ioutil.ReadAll(file)
-					if err != nil {
-						return nil, err
-					}
-					valueBuilder.setSimpleValue(string(data))
-				} else {
-					err := p.ParseWithBuilderAndSchema(file, resources.ForResource(path), valueBuilder, &dadlSchemaImpl{root: schemaNode})
-					if err != nil {
-						return nil, err
-					}
-				}
+				p.sourceMap.record(valueBuilder.getPath(), ctx.position(0))
+				ctx.document.outline.recordGroup(ctx.lineNo, ctx.schema, targetPath, valueBuilder.getPath())
+				files[i] = &importedFile{path: path, fileName: resourceFileName(ctx.document.fileName, path), schemaNode: schemaNode, builder: valueBuilder, pos: ctx.position(0)}
+			}
+			if err := p.parseImports(ctx.document, files, resources); err != nil {
+				return nil, err
 			}
 		} else {
-			schemaNode, valueBuilder, err = ctx.schema.getNode(treePath, rootBuilder, parseMetadata{lineNo: ctx.lineNo, colNo: 0})
+			schemaNode, valueBuilder, err = ctx.schema.getNode(treePath, rootBuilder, ctx.metadata(0))
 			if err != nil {
 				return nil, err
 			}
+			p.sourceMap.record(valueBuilder.getPath(), ctx.position(0))
+			ctx.document.outline.recordGroup(ctx.lineNo, ctx.schema, treePath, valueBuilder.getPath())
 
-			valueMeta, err = schemaNode.parse(valueBuilder, "", parseMetadata{lineNo: ctx.lineNo, colNo: 0})
+			valueMeta, err = schemaNode.parse(valueBuilder, "", ctx.metadata(0))
 			if err != nil {
 				return nil, err
 			}
 		}
-		return &parseContext{schema: ctx.schema, parentNodeInfo: &nodeInfo{valueType: schemaNode, builder: valueBuilder, valueMeta: valueMeta}, indentWeight: 0}, nil
+		return &parseContext{schema: ctx.schema, parentNodeInfo: &nodeInfo{valueType: schemaNode, builder: valueBuilder, valueMeta: valueMeta}, indentWeight: 0, document: ctx.document}, nil
 	}
 	return nil, errors.New("invalid group definition")
 }
 
+//resourceFileName describes resource imported from given file
+func resourceFileName(fileName string, resource string) string {
+	if fileName == "" || filepath.IsAbs(resource) || isURL(resource) {
+		return resource
+	}
+	if isURL(fileName) {
+		base, err := url.Parse(fileName)
+		if err != nil {
+			return resource
+		}
+		ref, err := url.Parse(resource)
+		if err != nil {
+			return resource
+		}
+		return base.ResolveReference(ref).String()
+	}
+	return filepath.Join(filepath.Dir(fileName), resource)
+}
+
 func calcIndentWeight(line string) int {
 	for idx, c := range line {
 		if !unicode.IsSpace(c) {
@@ -247,22 +448,42 @@ func calcIndentWeight(line string) int {
 
 func (p *Parser) parseMagic(ctx *parseContext, rootBuilder valueBuilder, line string, meta parseMetadata, resources ResourceProvider) error {
 	if strings.HasPrefix(line, "@schema ") {
-		var err error
-		parts := strings.Split(line[8:], " ")
+		parts := strings.Fields(line[8:])
 
 		if ctx.schema != nil {
 			//TODO compares chema
 			return nil
 		}
 
-		ctx.schema, err = parseSchema(parts[0], resources)
-		if err != nil {
-			return err
+		schemaName, subtree := parts[0], ""
+		for _, part := range parts[1:] {
+			if strings.HasPrefix(part, "sha256:") {
+				resources = pinnedResources{ResourceProvider: resources, name: schemaName, checksum: part}
+			} else if strings.HasPrefix(part, "[") && strings.HasSuffix(part, "]") {
+				subtree = part[1 : len(part)-1]
+			}
 		}
 
-		if len(parts) == 2 && strings.HasPrefix(parts[1], "[") && strings.HasSuffix(parts[1], "]") {
+		if schemaName == "dadl" {
+			ctx.schema = GetDadlSchema()
+		} else {
+			schemaFileName := resourceFileName(ctx.document.fileName, schemaName)
+			p.dependencies.add(Dependency{Kind: DependencySchema, File: schemaFileName, From: ctx.document.fileName})
+			if err := ctx.document.imports.push(schemaFileName); err != nil {
+				return newParseErrorAt(meta.position(), err.Error())
+			}
+			schema, err := ctx.document.schemas.load(schemaName, schemaFileName, resources, withImportStack(ctx.document.imports), WithSchemaCache(ctx.document.schemas))
+			ctx.document.imports.pop()
+			if err != nil {
+				return err
+			}
+			p.dependencies.merge(&schema.dependencies)
+			ctx.schema = schema.schema
+		}
+
+		if subtree != "" {
 			tmpRootBuilder := &dynamicMapOrListValueBuilder{value: Node{}}
-			valueType, _, err := ctx.schema.getNode(parts[1][1:len(parts[1])-1], tmpRootBuilder, parseMetadata{lineNo: ctx.lineNo, colNo: 0})
+			valueType, _, err := ctx.schema.getNode(subtree, tmpRootBuilder, ctx.metadata(0))
 			if err != nil {
 				return err
 			}
@@ -272,13 +493,105 @@ func (p *Parser) parseMagic(ctx *parseContext, rootBuilder valueBuilder, line st
 			ctx.parentNodeInfo = &nodeInfo{valueType: ctx.schema.getRoot(), builder: rootBuilder}
 		}
 		return nil
+	} else if strings.HasPrefix(line, "@overlay ") {
+		return p.parseOverlayBase(ctx, rootBuilder, strings.TrimSpace(line[9:]), meta, resources)
+	}
+	return newParseErrorAt(meta.position(), "Unknown magic line: "+line)
+}
+
+func (p *Parser) parseOverlayBase(ctx *parseContext, rootBuilder valueBuilder, basePath string, meta parseMetadata, resources ResourceProvider) error {
+	if _, ok := rootBuilder.(*streamBuilder); ok {
+		return newParseErrorAt(meta.position(), "@overlay is not supported while streaming")
+	}
+	if _, ok := rootBuilder.(*dynamicMapOrListValueBuilder); !ok || rootBuilder.getPath() != "" {
+		return newParseErrorAt(meta.position(), "@overlay is supported only in the main document")
+	}
+	if ctx.schema == nil {
+		return newParseErrorAt(meta.position(), "@overlay requires @schema to be defined first")
+	}
+	if ctx.document.overlayBase != nil {
+		return newParseErrorAt(meta.position(), "only one @overlay is allowed")
+	}
+
+	baseFileName := resourceFileName(ctx.document.fileName, basePath)
+	p.dependencies.add(Dependency{Kind: DependencyOverlay, File: baseFileName, From: ctx.document.fileName})
+	if err := ctx.document.imports.countImport(); err != nil {
+		return newParseErrorAt(meta.position(), err.Error())
+	}
+	if err := ctx.document.imports.push(baseFileName); err != nil {
+		return newParseErrorAt(meta.position(), err.Error())
+	}
+	defer ctx.document.imports.pop()
+	file, err := resources.GetResource(basePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	base := Node{}
+	sourceMap := p.sourceMap
+	if sourceMap != nil {
+		p.sourceMap = SourceMap{}
+	}
+	baseDocument := &documentContext{fileName: baseFileName, imports: ctx.document.imports, schemas: ctx.document.schemas}
+	if ctx.document.session != nil {
+		baseDocument.session = &parseSession{}
+	}
+	err = p.parseDocument(file, resources.ForResource(basePath), &dynamicMapOrListValueBuilder{value: base}, ctx.schema, baseDocument)
+	ctx.document.overlaySources = p.sourceMap
+	p.sourceMap = sourceMap
+	if err != nil {
+		return err
+	}
+	ctx.document.overlayBase = base
+	ctx.document.overlaySession = baseDocument.session
+	return nil
+}
+
+//applyOverlay merges values parsed from the document on top of its base
+func (p *Parser) applyOverlay(document *documentContext, rootBuilder valueBuilder) {
+	root := rootBuilder.(*dynamicMapOrListValueBuilder).value.(Node)
+	merged, overrides := Merge(document.overlayBase, root, p.mergeOptions)
+	for key := range root {
+		delete(root, key)
+	}
+	for key, value := range merged {
+		root[key] = value
+	}
+	for path, pos := range document.overlaySources {
+		p.sourceMap.record(path, pos)
+	}
+	if document.overlaySession != nil {
+		document.session.shiftAppended(overrides)
+		document.session.inheritDeferred(document.overlaySession, overrides)
+		document.session.inheritReferences(document.overlaySession, overrides)
+		document.session.inheritChecks(document.overlaySession, overrides)
+	}
+	if p.mergeReport != nil {
+		for _, override := range overrides {
+			override.Source = document.fileName
+			*p.mergeReport = append(*p.mergeReport, override)
+		}
 	}
-	return newParseError(meta.lineNo, meta.colNo, "Unknown magic line: "+line)
 }
 
 //Parser - parses DADL files
 type Parser struct {
 	// schema DadlSchema
+	fileName     string
+	sourceMap    SourceMap
+	mergeOptions MergeOptions
+	mergeReport  *MergeReport
+	envLookup    EnvLookup
+	resolveRefs  bool
+	outline      Outline
+	dependencies *Dependencies
+	//limits and stack of imports, the stack is set only for schemas parsed on behalf of a document
+	maxImportDepth   int
+	maxImportedFiles int
+	importStack      *importStack
+	schemas          []*Schema
+	schemaCache      *SchemaCache
 }
 
 //Node alias for map of string to interface
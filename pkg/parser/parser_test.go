@@ -1,11 +1,23 @@
 package parser
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"log"
+	"math/big"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/dadlang/dadl/pkg/export"
+	"github.com/dadlang/dadl/pkg/query"
 )
 
 func TestParserE2E(t *testing.T) {
@@ -29,6 +41,933 @@ func TestParserE2E(t *testing.T) {
 	}
 }
 
+func TestOverlayMergeReport(t *testing.T) {
+	file, err := os.Open("../../samples/overlay/prod.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	report := MergeReport{}
+	parser := NewParser(WithFileName("prod.dad"), WithMergeReport(&report), WithMergeOptions(MergeOptions{AppendLists: true}))
+	got, err := parser.Parse(file, NewFSResourceProvider("../../samples/overlay"))
+	if err != nil {
+		t.Fatalf("could not parse overlay, %v", err)
+	}
+	if tags := got["tags"]; !reflect.DeepEqual(tags, []interface{}{"boutique", "dev", "prod"}) {
+		t.Errorf("lists should be appended, got: %v", tags)
+	}
+
+	expected := []string{
+		"append cassandra.nodes: [map[host:node1 port:9042]] -> [map[host:prod1 port:9042] map[host:prod2 port:9042]] (prod.dad)",
+		"override cassandra.pass: admin123 -> secret (prod.dad)",
+		"append tags: [boutique dev] -> [prod] (prod.dad)",
+	}
+	if len(report) != len(expected) {
+		t.Fatalf("unexpected report: %v", report)
+	}
+	for i, override := range report {
+		if override.String() != expected[i] {
+			t.Errorf("GOT:  %s\nWANT: %s", override, expected[i])
+		}
+	}
+}
+
+func TestInterpolationErrors(t *testing.T) {
+	testCases := map[string]string{
+		"interpolation/cycle.dad":   "Parse error [file: cycle.dad, line: 4, col: 0]: reference cycle: cluster.name -> description -> cluster.name",
+		"interpolation/missing.dad": "Parse error [file: missing.dad, line: 5, col: 0]: reference to missing path: cluster.owner",
+		"interpolation/invalid.dad": "Parse error [file: invalid.dad, line: 5, col: 0]: invalid value after interpolation \"prod\": Invalid int value: prod",
+	}
+	for testFile, expected := range testCases {
+		fullPath := "../../samples/" + testFile
+		file, err := os.Open(fullPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		parser := NewParser(WithFileName(filepath.Base(fullPath)))
+		_, err = parser.Parse(file, NewFSResourceProvider(filepath.Dir(fullPath)))
+		if err == nil || err.Error() != expected {
+			t.Errorf("%s\nGOT:  %v\nWANT: %s", testFile, err, expected)
+		}
+	}
+}
+
+func TestEscapedReferences(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[structure]\nname string\nhome string\nport int\n"
+	testCases := []struct {
+		line     string
+		expected interface{}
+		err      string
+	}{
+		{line: "home $${HOME}", expected: "${HOME}"},
+		{line: "home $${HOME}/${name}", expected: "${HOME}/app"},
+		{line: "home $$${HOME}", expected: "$${HOME}"},
+		{line: "port $${PORT}", err: "Parse error [file: app.dad, line: 4, col: 0]: Invalid int value: ${PORT}"},
+	}
+	for _, tc := range testCases {
+		resources := NewMemoryResourceProvider(map[string]string{
+			"app.dad":  "@schema ./app.dads\n\nname app\n" + tc.line + "\n",
+			"app.dads": schema,
+		})
+		file, err := resources.GetResource("app.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parser := NewParser(WithFileName("app.dad"))
+		got, err := parser.Parse(file, resources)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s\nGOT:  %v\nWANT: %s", tc.line, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.line, err)
+		} else if value := got["home"]; value != tc.expected {
+			t.Errorf("%s\nGOT:  %v\nWANT: %v", tc.line, value, tc.expected)
+		}
+	}
+}
+
+func TestEnvSubstitution(t *testing.T) {
+	testCases := []struct {
+		env      map[string]string
+		enabled  bool
+		expected Node
+		err      string
+	}{
+		{
+			env:     map[string]string{"DADL_PORT": "9042", "DADL_PASS": "secret"},
+			enabled: true,
+			expected: Node{
+				"cassandra": Node{
+					"host": "localhost",
+					"port": 9042,
+					"pass": "secret",
+				},
+			},
+		},
+		{
+			env:     map[string]string{"DADL_HOST": "node1", "DADL_PORT": "9042", "DADL_PASS": ""},
+			enabled: true,
+			expected: Node{
+				"cassandra": Node{
+					"host": "node1",
+					"port": 9042,
+					"pass": "",
+				},
+			},
+		},
+		{
+			env:     map[string]string{"DADL_PORT": "9042"},
+			enabled: true,
+			err:     "Parse error [file: env.dad, line: 6, col: 5]: missing environment variable: DADL_PASS",
+		},
+		{
+			env:     map[string]string{"DADL_PORT": "port", "DADL_PASS": "secret"},
+			enabled: true,
+			err:     "Parse error [file: env.dad, line: 5, col: 0]: Invalid int value: port",
+		},
+		{
+			env: map[string]string{"DADL_PORT": "9042", "DADL_PASS": "secret"},
+			err: "Parse error [file: env.dad, line: 4, col: 0]: environment variable substitution is disabled: ${env:DADL_HOST:-localhost}",
+		},
+	}
+	for _, tc := range testCases {
+		file, err := os.Open("../../samples/env/env.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		options := []Option{WithFileName("env.dad")}
+		if tc.enabled {
+			options = append(options, WithEnvSubstitution(func(name string) (string, bool) {
+				value, ok := tc.env[name]
+				return value, ok
+			}))
+		}
+		parser := NewParser(options...)
+		got, err := parser.Parse(file, NewFSResourceProvider("../../samples/env"))
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("GOT:  %v\nWANT: %s", err, tc.err)
+			}
+		} else if err != nil {
+			t.Errorf("could not parse env.dad, %v", err)
+		} else if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("GOT:  %+v\nWANT: %+v", got, tc.expected)
+		}
+	}
+}
+
+func TestEnvSubstitutionIsLiteral(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[structure]\na string\nb string\nc string\nnote string\n"
+	testCases := []struct {
+		pw       string
+		expected Node
+	}{
+		{pw: "${a}", expected: Node{"a": "x", "b": "${a}", "c": "${a}-x${a}{a}", "note": "text\n${a}"}},
+		{pw: "x$${y", expected: Node{"a": "x", "b": "x$${y", "c": "x$${y-xx$${y{a}", "note": "text\nx$${y"}},
+		{pw: "x$", expected: Node{"a": "x", "b": "x$", "c": "x$-xx${a}", "note": "text\nx$"}},
+	}
+	for _, tc := range testCases {
+		resources := NewMemoryResourceProvider(map[string]string{
+			"app.dad":  "@schema ./app.dads\n\na x\nb ${env:PW}\nc ${env:PW}-${a}${env:PW}{a}\nnote text\n\t${env:PW}\n",
+			"app.dads": schema,
+		})
+		file, err := resources.GetResource("app.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parser := NewParser(WithFileName("app.dad"), WithEnvSubstitution(func(name string) (string, bool) {
+			return tc.pw, name == "PW"
+		}))
+		got, err := parser.Parse(file, resources)
+		if err != nil {
+			t.Errorf("PW=%s: %v", tc.pw, err)
+		} else if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("PW=%s\nGOT:  %+v\nWANT: %+v", tc.pw, got, tc.expected)
+		}
+	}
+}
+
+func TestReferences(t *testing.T) {
+	testCases := []struct {
+		testFile string
+		options  []Option
+		path     string
+		expected interface{}
+		err      string
+	}{
+		{
+			testFile: "refs.dad",
+			path:     "modules.cart.rest[2].interactor",
+			expected: "EmptyWishlist",
+		},
+		{
+			testFile: "refs.dad",
+			options:  []Option{WithResolvedRefs()},
+			path:     "modules.cart.rest[2].interactor",
+			expected: Node{"name": "Empty Wishlist"},
+		},
+		{
+			testFile: "refs.dad",
+			options:  []Option{WithResolvedRefs()},
+			path:     "owner",
+			expected: Node{"email": "alice@example.com"},
+		},
+		{
+			testFile: "dangling.dad",
+			err:      "Parse error [file: dangling.dad, line: 3, col: 0]: dangling reference: carol not found in users",
+		},
+	}
+	for _, tc := range testCases {
+		file, err := os.Open("../../samples/refs/" + tc.testFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		parser := NewParser(append([]Option{WithFileName(tc.testFile)}, tc.options...)...)
+		got, err := parser.Parse(file, NewFSResourceProvider("../../samples/refs"))
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("GOT:  %v\nWANT: %s", err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("could not parse %s, %v", tc.testFile, err)
+		}
+		value, err := query.Get(got, tc.path)
+		if err != nil {
+			t.Fatalf("missing %s, %v", tc.path, err)
+		}
+		if !reflect.DeepEqual(value, tc.expected) {
+			t.Errorf("%s\nGOT:  %+v\nWANT: %+v", tc.path, value, tc.expected)
+		}
+	}
+}
+
+func TestOutline(t *testing.T) {
+	file, err := os.Open("../../samples/lsp/lsp.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	outline := Outline{}
+	parser := NewParser(WithOutline(outline))
+	if _, err := parser.Parse(file, NewFSResourceProvider("../../samples/lsp")); err != nil {
+		t.Fatalf("could not parse lsp.dad, %v", err)
+	}
+	expected := map[int]LineSchema{
+		3: {Path: "name", Type: "string", Doc: "Name of the project", Keys: []string{"modules", "name", "server"}},
+		5: {Path: "server", Type: "struct", Doc: "Server settings", Keys: []string{"modules", "name", "server"}},
+		6: {Path: "server.host", Type: "string `\\S+`", Doc: "Host name", Keys: []string{"host", "port"}},
+		7: {Path: "server.port", Type: "int 0..65535", Doc: "Network port", Keys: []string{"host", "port"}},
+		9: {Path: "modules.cart", Type: "struct"},
+	}
+	for lineNo, want := range expected {
+		if got := outline[lineNo]; got == nil || !reflect.DeepEqual(*got, want) {
+			t.Errorf("line %d\nGOT:  %+v\nWANT: %+v", lineNo, got, want)
+		}
+	}
+	if len(outline) != len(expected)+1 || outline.LastLine() != 9 {
+		t.Errorf("unexpected lines: %v", outline)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	file, err := os.Open("../../samples/refs/refs.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	dependencies := &Dependencies{}
+	parser := NewParser(WithFileName("refs.dad"), WithDependencies(dependencies))
+	if _, err := parser.Parse(file, NewFSResourceProvider("../../samples/refs")); err != nil {
+		t.Fatalf("could not parse refs.dad, %v", err)
+	}
+	expected := &Dependencies{
+		Files: []Dependency{
+			{Kind: DependencySchema, File: "refs.dads", From: "refs.dad"},
+			{Kind: DependencyImport, File: "modules/cart.dad", From: "refs.dad", Path: "modules.cart"},
+			{Kind: DependencyImport, File: "modules/wishlist.dad", From: "refs.dad", Path: "modules.wishlist"},
+		},
+		Patterns: []string{"modules/*.dad"},
+	}
+	if !reflect.DeepEqual(dependencies, expected) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", dependencies, expected)
+	}
+}
+
+func TestImportCyclesAndLimits(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[types]\nnode struct\n    name string\n    children map[string]node\n\n[structure]\nname string\nchildren map[string]node\n"
+	testCases := []struct {
+		files   map[string]string
+		options []Option
+		err     string
+	}{
+		{
+			files: map[string]string{
+				"app.dad":       "@schema ./app.dads\n\n[children._ < ./modules/*.dad]\n",
+				"app.dads":      "@schema ./app.dads\n",
+				"modules/a.dad": "name a\n",
+			},
+			err: "Parse error [file: app.dads, line: 1, col: 0]: import cycle: app.dads -> app.dads",
+		},
+		{
+			files: map[string]string{
+				"app.dad":  "@schema ./app.dads\n\n[children._ < ./*.dad]\n",
+				"app.dads": schema,
+			},
+			err: "Parse error [file: app.dad, line: 3, col: 0]: import cycle: app.dad -> app.dad",
+		},
+		{
+			files: map[string]string{
+				"app.dad":       "@schema ./app.dads\n\n[children.a < ./modules/a.dad]\n",
+				"app.dads":      schema,
+				"modules/a.dad": "[children.b < ./b.dad]\n",
+				"modules/b.dad": "[children.a < ./a.dad]\n",
+			},
+			err: "Parse error [file: modules/b.dad, line: 1, col: 0]: import cycle: modules/a.dad -> modules/b.dad -> modules/a.dad",
+		},
+		{
+			files: map[string]string{
+				"app.dad":       "@schema ./app.dads\n\n[children.a < ./modules/a.dad]\n",
+				"app.dads":      schema,
+				"modules/a.dad": "[children.b < ./b.dad]\n",
+				"modules/b.dad": "name b\n",
+			},
+			options: []Option{WithMaxImportDepth(1)},
+			err:     "Parse error [file: modules/a.dad, line: 1, col: 0]: import depth limit of 1 exceeded: app.dad -> modules/a.dad -> modules/b.dad",
+		},
+		{
+			files: map[string]string{
+				"app.dad":       "@schema ./app.dads\n\n[children._ < ./modules/*.dad]\n",
+				"app.dads":      schema,
+				"modules/a.dad": "name a\n",
+				"modules/b.dad": "name b\n",
+				"modules/c.dad": "name c\n",
+			},
+			options: []Option{WithMaxImportedFiles(2)},
+			err:     "Parse error [file: app.dad, line: 3, col: 0]: imported files limit of 2 exceeded",
+		},
+	}
+	for _, tc := range testCases {
+		resources := NewMemoryResourceProvider(tc.files)
+		file, err := resources.GetResource("app.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parser := NewParser(append([]Option{WithFileName("app.dad")}, tc.options...)...)
+		_, err = parser.Parse(file, resources)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("GOT:  %v\nWANT: %s", err, tc.err)
+		}
+	}
+}
+
+func TestConcurrentImports(t *testing.T) {
+	files := map[string]string{
+		"app.dad":  "@schema ./app.dads\n\nname app\n\n[children._ < ./modules/*.dad]\n",
+		"app.dads": "@schema dadl 0.1\n\n[types]\nnode struct\n    name string\n    children map[string]node\n\n[structure]\nname string\nchildren map[string]node\n",
+	}
+	expected := Node{"name": "app", "children": Node{}}
+	expectedDependencies := &Dependencies{
+		Files:    []Dependency{{Kind: DependencySchema, File: "app.dads", From: "app.dad"}},
+		Patterns: []string{"modules/*.dad"},
+	}
+	for i := 0; i < 30; i++ {
+		module := fmt.Sprintf("m%02d", i)
+		files["modules/"+module+".dad"] = "name " + module + "\n\n[children._ < ./" + module + "/*.dad]\n"
+		files["modules/"+module+"/a.dad"] = "name " + module + "a\n"
+		files["modules/"+module+"/b.dad"] = "name " + module + "b\n"
+		expected["children"].(Node)[module] = Node{
+			"name": module,
+			"children": Node{
+				"a": Node{"name": module + "a"},
+				"b": Node{"name": module + "b"},
+			},
+		}
+		expectedDependencies.Files = append(expectedDependencies.Files,
+			Dependency{Kind: DependencyImport, File: "modules/" + module + ".dad", From: "app.dad", Path: "children." + module},
+			Dependency{Kind: DependencyImport, File: "modules/" + module + "/a.dad", From: "modules/" + module + ".dad", Path: "children." + module + ".children.a"},
+			Dependency{Kind: DependencyImport, File: "modules/" + module + "/b.dad", From: "modules/" + module + ".dad", Path: "children." + module + ".children.b"},
+		)
+		expectedDependencies.Patterns = append(expectedDependencies.Patterns, "modules/"+module+"/*.dad")
+	}
+	resources := NewMemoryResourceProvider(files)
+
+	parse := func() (Node, *Dependencies, SourceMap, error) {
+		file, err := resources.GetResource("app.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dependencies := &Dependencies{}
+		sourceMap := SourceMap{}
+		parser := NewParser(WithFileName("app.dad"), WithDependencies(dependencies), WithSourceMap(sourceMap))
+		tree, err := parser.Parse(file, resources)
+		return tree, dependencies, sourceMap, err
+	}
+
+	tree, dependencies, sourceMap, err := parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", tree, expected)
+	}
+	if !reflect.DeepEqual(dependencies, expectedDependencies) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", dependencies, expectedDependencies)
+	}
+	if pos, ok := sourceMap.Lookup("children.m07.children.b.name"); !ok || pos != (Position{File: "modules/m07/b.dad", Line: 1}) {
+		t.Errorf("GOT:  %+v\nWANT: modules/m07/b.dad line 1", pos)
+	}
+
+	//first failing file in path order is reported no matter which one fails first
+	resources.Add("modules/m03.dad", "[children.self < ./m03.dad]\n")
+	resources.Add("modules/m21.dad", "[children.self < ./m21.dad]\n")
+	expectedErr := "Parse error [file: modules/m03.dad, line: 1, col: 0]: import cycle: modules/m03.dad -> modules/m03.dad"
+	for i := 0; i < 5; i++ {
+		if _, _, _, err := parse(); err == nil || err.Error() != expectedErr {
+			t.Errorf("GOT:  %v\nWANT: %s", err, expectedErr)
+		}
+	}
+}
+
+func TestNumberValues(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[structure]\nratio number 0..<1\nangle number -180<..180\nany number\n"
+	prefix := "Parse error [file: app.dad, line: 3, col: 0]: "
+	testCases := []valueTestCase{
+		{line: "ratio 0", expected: 0.0},
+		{line: "ratio 0.999", expected: 0.999},
+		{line: "ratio 1", err: prefix + "Number out of range 0..<1: 1"},
+		{line: "ratio -1e-9", err: prefix + "Number out of range 0..<1: -1e-9"},
+		{line: "angle 180", expected: 180.0},
+		{line: "angle -180", err: prefix + "Number out of range -180<..180: -180"},
+		{line: "any -.5E+3", expected: -500.0},
+		{line: "any 1e400", err: prefix + "Invalid number value: 1e400"},
+		{line: "any one", err: prefix + "Invalid number value: one"},
+		{line: "any NaN", err: prefix + "Invalid number value: NaN"},
+		{line: "any 0x1p-2", err: prefix + "Invalid number value: 0x1p-2"},
+		{line: "any 1_000.5", err: prefix + "Invalid number value: 1_000.5"},
+		{line: "any Inf", err: prefix + "Invalid number value: Inf"},
+	}
+	testValues(t, schema, testCases)
+}
+
+func TestIntValues(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[structure]\nport int 0..65535\ncount int 1..\nbyte uint8\nsmall int8 -10..\nhuge uint64\nany int\n"
+	testCases := []valueTestCase{
+		{line: "port 8080", expected: 8080},
+		{line: "port 0x1F_90", expected: 8080},
+		{line: "port 70000", err: "Parse error [file: app.dad, line: 3, col: 0]: Int out of range 0..65535: 70000"},
+		{line: "count 1_000_000_000_000", expected: big.NewInt(1000000000000)},
+		{line: "count 0", err: "Parse error [file: app.dad, line: 3, col: 0]: Int out of range 1..: 0"},
+		{line: "byte 0b1111_1111", expected: uint8(255)},
+		{line: "byte -1", err: "Parse error [file: app.dad, line: 3, col: 0]: Int out of range 0..255: -1"},
+		{line: "small 0o17", expected: int8(15)},
+		{line: "small -11", err: "Parse error [file: app.dad, line: 3, col: 0]: Int out of range -10..127: -11"},
+		{line: "huge 18446744073709551615", expected: uint64(18446744073709551615)},
+		{line: "any 010", expected: big.NewInt(10)},
+		{line: "any 1__0", err: "Parse error [file: app.dad, line: 3, col: 0]: Invalid int value: 1__0"},
+		{line: "any 0x", err: "Parse error [file: app.dad, line: 3, col: 0]: Invalid int value: 0x"},
+	}
+	testValues(t, schema, testCases)
+}
+
+func TestStringValues(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[types]\nword string `\\S+`\n\n[structure]\nhost string `\\S+`\nname string minLen 2 maxLen 5\ntext string maxLen 12\nraw string notrim\nid identifier\nwords map[string]word\n"
+	testCases := []valueTestCase{
+		{line: "host example.com", expected: "example.com"},
+		{line: "host example com", err: "Parse error [file: app.dad, line: 3, col: 0]: Value \"example com\" doesn't match pattern `\\S+`"},
+		{line: "name abcde", expected: "abcde"},
+		{line: "name żółw", expected: "żółw"},
+		{line: "name a", err: "Parse error [file: app.dad, line: 3, col: 0]: Value \"a\" is shorter than 2 characters"},
+		{line: "name abcdef", err: "Parse error [file: app.dad, line: 3, col: 0]: Value \"abcdef\" is longer than 5 characters"},
+		{line: "text\n\tfirst\n\tsecond", expected: "first\nsecond"},
+		{line: "text\n\tfirst\n\tsecond\n\tthird", err: "Parse error [file: app.dad, line: 3, col: 0]: Value \"first\\nsecond\\nthird\" is longer than 12 characters"},
+		{line: "raw\n\tfirst\n\t\tsecond", expected: "\tfirst\n\t\tsecond"},
+		{line: "words\n\tit's xy", expected: map[string]interface{}{"it's": "xy"}},
+		{line: "words\n\tit's x y", err: "Parse error [file: app.dad, line: 4, col: 0]: Value \"x y\" doesn't match pattern `\\S+`"},
+		{line: "id some id", err: "Parse error [file: app.dad, line: 3, col: 0]: Value \"some id\" doesn't match pattern `[A-Za-z-0-9_-]+`"},
+	}
+	testValues(t, schema, testCases)
+
+	//streamed values are checked when they are complete as well
+	for _, tc := range testCases {
+		_, err := parseLine(t, schema, tc.line)
+		resources := NewMemoryResourceProvider(lineFiles(schema, tc.line))
+		file, _ := resources.GetResource("app.dad")
+		parser := NewParser(WithFileName("app.dad"))
+		streamErr := parser.Stream(file, resources, func(event Event) error { return nil })
+		if fmt.Sprint(streamErr) != fmt.Sprint(err) {
+			t.Errorf("%s\nGOT:  %v\nWANT: %v", tc.line, streamErr, err)
+		}
+	}
+}
+
+func TestOverlayValueChecks(t *testing.T) {
+	resources := NewMemoryResourceProvider(map[string]string{
+		"app.dads": "@schema dadl 0.1\n\n[types]\nword string `\\S+`\n\n[structure]\nnames list[word]\nhost word\n",
+		"base.dad": "@schema ./app.dads\n\nnames\n    one\n    two\n    three\nhost base\n",
+		"prod.dad": "@schema ./app.dads\n@overlay ./base.dad\n\nnames\n    four\nhost prod host\n",
+	})
+	file, err := resources.GetResource("prod.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//checks of list items removed by the overlay are dropped, the value set by the overlay is still checked
+	parser := NewParser(WithFileName("prod.dad"))
+	_, err = parser.Parse(file, resources)
+	expected := "Parse error [file: prod.dad, line: 6, col: 0]: Value \"prod host\" doesn't match pattern `\\S+`"
+	if err == nil || err.Error() != expected {
+		t.Errorf("GOT:  %v\nWANT: %s", err, expected)
+	}
+}
+
+func TestOverlayAppendedLists(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[types]\nhost string `[a-z.]+`\n\n[structure]\nname string\nhosts list[host]\nowners list[ref[users]]\nusers map[string]\n    email string\n"
+	base := "@schema ./app.dads\n\nname cluster\n\n[users]\nalice\n    email alice@example.com\nbob\n    email bob@example.com\n\n[hosts]\nbase.one\nbase.two\n\n[owners]\nalice\n"
+	testCases := []struct {
+		overlay  string
+		expected Node
+		err      string
+	}{
+		{
+			overlay: "[hosts]\nprod.${name}\n\n[owners]\nbob\n",
+			expected: Node{
+				"hosts":  []interface{}{"base.one", "base.two", "prod.cluster"},
+				"owners": []interface{}{Node{"email": "alice@example.com"}, Node{"email": "bob@example.com"}},
+			},
+		},
+		{
+			overlay: "[hosts]\nBAD VALUE\n",
+			err:     "Parse error [file: app.dad, line: 5, col: 0]: Value \"BAD VALUE\" doesn't match pattern `[a-z.]+`",
+		},
+	}
+	for _, tc := range testCases {
+		resources := NewMemoryResourceProvider(map[string]string{
+			"app.dad":  "@schema ./app.dads\n@overlay ./base.dad\n\n" + tc.overlay,
+			"app.dads": schema,
+			"base.dad": base,
+		})
+		file, err := resources.GetResource("app.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		parser := NewParser(WithFileName("app.dad"), WithMergeOptions(MergeOptions{AppendLists: true}), WithResolvedRefs())
+		got, err := parser.Parse(file, resources)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("GOT:  %v\nWANT: %s", err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("could not parse app.dad, %v", err)
+		}
+		for key, value := range tc.expected {
+			if !reflect.DeepEqual(got[key], value) {
+				t.Errorf("%s\nGOT:  %+v\nWANT: %+v", key, got[key], value)
+			}
+		}
+	}
+}
+
+func TestFormatValues(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[structure]\nmail email\nsite url https|http\nlink uri\nv4 ipv4\nv6 ipv6\naddr ip\nnet cidr\nhost hostname\nid uuid\nversion semver\n" +
+		"endpoint formula <host hostname> ':' <port int>\npeer formula '[' <addr ipv6> ']:' <port int>\n"
+	prefix := "Parse error [file: app.dad, line: 3, col: 0]: "
+	testCases := []valueTestCase{
+		{line: "mail john.doe@Example.COM", expected: "john.doe@example.com"},
+		{line: "mail John <john@example.com>", err: prefix + "Invalid email value: John <john@example.com> (expected address without display name)"},
+		{line: "site HTTPS://Example.com/a?b=c", expected: "https://example.com/a?b=c"},
+		{line: "site ftp://example.com", err: prefix + "Invalid url value: ftp://example.com (scheme must be one of https, http)"},
+		{line: "site https:///path", err: prefix + "Invalid url value: https:///path (missing host)"},
+		{line: "link mailto:john@example.com", expected: "mailto:john@example.com"},
+		{line: "link /relative/path", err: prefix + "Invalid uri value: /relative/path (missing scheme)"},
+		{line: "v4 192.168.0.1", expected: "192.168.0.1"},
+		{line: "v4 192.168.0.256", err: prefix + "Invalid ipv4 value: 192.168.0.256 (expected four decimal octets)"},
+		{line: "v4 ::1", err: prefix + "Invalid ipv4 value: ::1 (expected four decimal octets)"},
+		{line: "v6 2001:0DB8:0000:0000:0000:0000:0000:0001", expected: "2001:db8::1"},
+		{line: "v6 ::FFFF:10.0.0.1", expected: "::ffff:10.0.0.1"},
+		{line: "v6 10.0.0.1", err: prefix + "Invalid ipv6 value: 10.0.0.1 (expected colon separated hexadecimal groups)"},
+		{line: "addr fe80:0::1", expected: "fe80::1"},
+		{line: "addr 10.0.0.1", expected: "10.0.0.1"},
+		{line: "net 10.1.2.3/8", expected: "10.1.2.3/8"},
+		{line: "net 2001:DB8::/32", expected: "2001:db8::/32"},
+		{line: "net 10.0.0.0/33", err: prefix + "Invalid cidr value: 10.0.0.0/33 (expected address and prefix length, e.g. 10.0.0.0/8)"},
+		{line: "host API.Example.com.", expected: "api.example.com"},
+		{line: "host -api.example.com", err: prefix + "Invalid hostname value: -api.example.com (label \"-api\" can't start or end with hyphen)"},
+		{line: "host api_1.example.com", err: prefix + "Invalid hostname value: api_1.example.com (label \"api_1\" contains invalid character '_')"},
+		{line: "id {123E4567-E89B-12D3-A456-426614174000}", expected: "123e4567-e89b-12d3-a456-426614174000"},
+		{line: "id urn:uuid:123e4567-e89b-12d3-a456-426614174000", expected: "123e4567-e89b-12d3-a456-426614174000"},
+		{line: "id 123e4567e89b12d3a456426614174000", err: prefix + "Invalid uuid value: 123e4567e89b12d3a456426614174000 (expected 5 groups of hexadecimal digits)"},
+		{line: "version v1.2.3-rc.1+build.5", expected: "1.2.3-rc.1+build.5"},
+		{line: "version 1.02.3", err: prefix + "Invalid semver value: 1.02.3 (invalid version number \"02\")"},
+		{line: "version 1.2.3-rc.01", err: prefix + "Invalid semver value: 1.2.3-rc.01 (pre-release: leading zero in \"01\")"},
+		{line: "endpoint DB.local:5432", expected: map[string]interface{}{"host": "db.local", "port": big.NewInt(5432)}},
+		{line: "endpoint db-.local:5432", err: prefix + "Invalid hostname value: db-.local (label \"db-\" can't start or end with hyphen)"},
+		{line: "peer [2001:db8:0::1]:443", expected: map[string]interface{}{"addr": "2001:db8::1", "port": big.NewInt(443)}},
+	}
+	testValues(t, schema, testCases)
+}
+
+func TestTimeValues(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[structure]\nday date 2020-01-01..\nopens time 06:00..22:00\nat datetime\ntimeout duration 1s..10m\nany duration\n" +
+		"window formula <from time> '-' <to time> ' every ' <period duration>\n"
+	prefix := "Parse error [file: app.dad, line: 3, col: 0]: "
+	testCases := []valueTestCase{
+		{line: "day 2024-02-29", expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), exported: "2024-02-29"},
+		{line: "day 2023-02-29", err: prefix + "Invalid date value: 2023-02-29"},
+		{line: "day 2019-12-31", err: prefix + "Date out of range 2020-01-01..: 2019-12-31"},
+		{line: "opens 08:30", expected: time.Date(0, 1, 1, 8, 30, 0, 0, time.UTC), exported: "08:30:00"},
+		{line: "opens 21:59:59.5", expected: time.Date(0, 1, 1, 21, 59, 59, 500000000, time.UTC), exported: "21:59:59.5"},
+		{line: "opens 22:00:01", err: prefix + "Time out of range 06:00..22:00: 22:00:01"},
+		{line: "at 2024-05-01T12:00:00+02:00", expected: time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("", 7200)), exported: "2024-05-01T12:00:00+02:00"},
+		{line: "at 2024-05-01t00:00:00z", expected: time.Date(2024, 5, 1, 0, 0, 0, 0, time.FixedZone("", 0)), exported: "2024-05-01T00:00:00Z"},
+		{line: "at 2024-05-01T12:00:00", err: prefix + "Invalid datetime value: 2024-05-01T12:00:00"},
+		{line: "timeout 1m30s", expected: 90 * time.Second, exported: "PT1M30S"},
+		{line: "timeout PT0.5S", err: prefix + "Duration out of range 1s..10m: PT0.5S"},
+		{line: "timeout 10m0.001s", err: prefix + "Duration out of range 1s..10m: 10m0.001s"},
+		{line: "any P1W2DT3H4M5.25S", expected: 9*24*time.Hour + 3*time.Hour + 4*time.Minute + 5250*time.Millisecond, exported: "PT219H4M5.25S"},
+		{line: "any -PT1H", expected: -time.Hour, exported: "-PT1H"},
+		{line: "any 0", expected: time.Duration(0), exported: "PT0S"},
+		{line: "any P1M", err: prefix + "Invalid duration value: P1M, years and months have no fixed length"},
+		{line: "any PT1S1M", err: prefix + "Invalid duration value: PT1S1M"},
+		{line: "any 1 hour", err: prefix + "Invalid duration value: 1 hour"},
+		{line: "window 09:00-17:30 every 24h", expected: map[string]interface{}{
+			"from":   time.Date(0, 1, 1, 9, 0, 0, 0, time.UTC),
+			"to":     time.Date(0, 1, 1, 17, 30, 0, 0, time.UTC),
+			"period": 24 * time.Hour,
+		}, exported: map[string]interface{}{"from": "09:00:00", "to": "17:30:00", "period": "PT24H"}},
+	}
+	testValues(t, schema, testCases)
+
+	resources := NewMemoryResourceProvider(map[string]string{"app.dads": "@schema dadl 0.1\n\n[structure]\ntimeout duration 1sec..\n"})
+	if _, err := CompileSchema("app.dads", resources); err == nil || err.Error() != "Invalid duration range bound: 1sec" {
+		t.Errorf("GOT:  %v\nWANT: Invalid duration range bound: 1sec", err)
+	}
+}
+
+func TestTimeReferences(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[structure]\nstart date\nend date\nlabel string\nat datetime\nnext datetime\ntimeout duration\nretry duration\n"
+	got, err := parseLine(t, schema, "start 2024-02-29\nend ${start}\nlabel from ${start} at ${at} for ${timeout}\nat 2024-05-01T12:00:00+02:00\nnext ${at}\ntimeout 1m30s\nretry ${timeout}")
+	if err != nil {
+		t.Fatalf("could not parse app.dad, %v", err)
+	}
+	expected := Node{
+		"start":   time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"end":     time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		"label":   "from 2024-02-29 at 2024-05-01T12:00:00+02:00 for PT1M30S",
+		"at":      time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("", 7200)),
+		"next":    time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("", 7200)),
+		"timeout": 90 * time.Second,
+		"retry":   90 * time.Second,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", got, expected)
+	}
+}
+
+func TestSchemaCache(t *testing.T) {
+	resources := NewFSResourceProvider("../../samples/refs")
+	parse := func(options ...Option) {
+		file, err := resources.GetResource("refs.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		parser := NewParser(append([]Option{WithFileName("refs.dad")}, options...)...)
+		if _, err := parser.Parse(file, resources); err != nil {
+			t.Fatalf("could not parse refs.dad, %v", err)
+		}
+	}
+
+	//the main document and both imported modules refer to the same schema
+	cache := NewSchemaCache()
+	parse(WithSchemaCache(cache))
+	if len(cache.schemas) != 1 {
+		t.Errorf("unexpected cached schemas: %v", cache.schemas)
+	}
+
+	schema, err := CompileSchema("refs.dads", resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache = NewSchemaCache()
+	parse(WithSchema(schema), WithSchemaCache(cache))
+	if len(cache.schemas) != 1 || cache.schemas[schemaKey{fileName: schema.fileName, checksum: schema.checksum}] != schema {
+		t.Errorf("compiled schema not used: %v", cache.schemas)
+	}
+
+	memory := NewMemoryResourceProvider(map[string]string{
+		"app.dads": "@schema dadl 0.1\n\n[structure]\nname string\n",
+		"app.dad":  "@schema ./app.dads\n\nname app\n",
+	})
+	for _, schema := range []string{"@schema dadl 0.1\n\n[structure]\nname string\n", "@schema dadl 0.1\n\n[structure]\nname identifier\n"} {
+		memory.Add("app.dads", schema)
+		file, _ := memory.GetResource("app.dad")
+		parser := NewParser(WithFileName("app.dad"), WithSchemaCache(cache))
+		if _, err := parser.Parse(file, memory); err != nil {
+			t.Fatalf("could not parse app.dad, %v", err)
+		}
+	}
+	if len(cache.schemas) != 3 {
+		t.Errorf("changed schema not compiled again: %v", cache.schemas)
+	}
+}
+
+//TestConcurrentParsing checks that compiled schema can be shared by concurrent parsers, run with -race
+func TestConcurrentParsing(t *testing.T) {
+	resources := NewFSResourceProvider("../../samples")
+	for _, tc := range testCases {
+		schemaName := ""
+		data, err := ioutil.ReadFile("../../samples/" + tc.testFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match := regexp.MustCompile(`^@schema (\S+)`).FindStringSubmatch(string(data)); match != nil {
+			schemaName = path.Join(path.Dir(tc.testFile), match[1])
+		}
+		schema, err := CompileSchema(schemaName, resources)
+		if err != nil {
+			t.Fatalf("%s: could not compile %s, %v", tc.name, schemaName, err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				parser := NewParser(WithFileName(tc.testFile), WithSchema(schema))
+				got, err := parser.Parse(bytes.NewReader(data), resources.ForResource(tc.testFile))
+				if err == nil && !reflect.DeepEqual(got, tc.expected) {
+					err = fmt.Errorf("GOT:  %+v\nWANT: %+v", got, tc.expected)
+				}
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Errorf("%s: %v", tc.name, err)
+			}
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	for _, tc := range testCases {
+		if tc.testFile == "overlay/prod.dad" || tc.testFile == "interpolation/interpolation.dad" {
+			continue
+		}
+		fullPath := "../../samples/" + tc.testFile
+		file, err := os.Open(fullPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		//rebuild the tree from events to compare it with the parsed one
+		var stack []interface{}
+		var got Node
+		set := func(event Event, value interface{}) {
+			switch parent := stack[len(stack)-1].(type) {
+			case Node:
+				parent[event.Key] = value
+			case *[]interface{}:
+				for len(*parent) <= event.Index {
+					*parent = append(*parent, nil)
+				}
+				(*parent)[event.Index] = value
+			}
+		}
+		depth := 0
+		parser := NewParser(WithFileName(tc.testFile))
+		err = parser.Stream(file, NewFSResourceProvider(filepath.Dir(fullPath)), func(event Event) error {
+			switch event.Kind {
+			case EventStartNode:
+				depth++
+				node := Node{}
+				if len(stack) == 0 {
+					got = node
+				} else if parent, ok := stack[len(stack)-1].(Node); ok && parent[event.Key] != nil {
+					//node filled in again by a group
+					node = parent[event.Key].(Node)
+				} else {
+					set(event, node)
+				}
+				stack = append(stack, node)
+			case EventStartList:
+				depth++
+				list := &[]interface{}{}
+				set(event, list)
+				stack = append(stack, list)
+			case EventEndNode, EventEndList:
+				depth--
+				stack = stack[:len(stack)-1]
+			case EventValue:
+				set(event, event.Value)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("could not stream %v, %v", tc.testFile, err)
+		}
+		if depth != 0 {
+			t.Errorf("%s: %d nodes not ended", tc.name, depth)
+		}
+		if !reflect.DeepEqual(resolveStreamedLists(got), tc.expected) {
+			t.Errorf("%s[%s]\nGOT:  %+v \nWANT: %+v", tc.name, tc.testFile, got, tc.expected)
+		}
+	}
+
+	//error returned by the handler stops parsing
+	stop := errors.New("stop")
+	file, err := os.Open("../../samples/formula/formula.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var last Event
+	parser := NewParser(WithFileName("formula.dad"))
+	err = parser.Stream(file, NewFSResourceProvider("../../samples/formula"), func(event Event) error {
+		last = event
+		if event.Kind == EventValue && event.Value == "getCart" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || last.Path != "nodes[0].children[0].children[0].interactor" || last.Position.Line != 6 {
+		t.Errorf("GOT:  %v, %+v\nWANT: %v at nodes[0].children[0].children[0].interactor, line 6", err, last, stop)
+	}
+}
+
+func resolveStreamedLists(value interface{}) interface{} {
+	switch v := value.(type) {
+	case Node:
+		for key, item := range v {
+			v[key] = resolveStreamedLists(item)
+		}
+	case *[]interface{}:
+		for i, item := range *v {
+			(*v)[i] = resolveStreamedLists(item)
+		}
+		return *v
+	}
+	return value
+}
+
+//lineFiles returns app.dad made of given lines, which start at line 3, and its schema app.dads
+func lineFiles(schema string, line string) map[string]string {
+	return map[string]string{
+		"app.dad":  "@schema ./app.dads\n\n" + line + "\n",
+		"app.dads": schema,
+	}
+}
+
+//parseLine parses given lines of app.dad with the schema
+func parseLine(t *testing.T, schema string, line string) (Node, error) {
+	t.Helper()
+	resources := NewMemoryResourceProvider(lineFiles(schema, line))
+	file, err := resources.GetResource("app.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parser := NewParser(WithFileName("app.dad"))
+	return parser.Parse(file, resources)
+}
+
+//valueTestCase is a line with the value it's parsed to or the expected parse error
+type valueTestCase struct {
+	line     string
+	expected interface{}
+	//exported is the value exported to JSON and YAML, it's checked when set
+	exported interface{}
+	err      string
+}
+
+//testValues checks the only value of every parsed line, parsed structs are compared as maps
+func testValues(t *testing.T, schema string, testCases []valueTestCase) {
+	t.Helper()
+	for _, tc := range testCases {
+		tree, err := parseLine(t, schema, tc.line)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s\nGOT:  %v\nWANT: %s", tc.line, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.line, err)
+			continue
+		}
+		for _, value := range tree {
+			if node, ok := value.(Node); ok {
+				value = map[string]interface{}(node)
+			}
+			if !reflect.DeepEqual(value, tc.expected) {
+				t.Errorf("%s\nGOT:  %#v\nWANT: %#v", tc.line, value, tc.expected)
+			}
+			if exported := export.Value(value); tc.exported != nil && !reflect.DeepEqual(exported, tc.exported) {
+				t.Errorf("%s\nGOT:  %#v\nWANT: %#v", tc.line, exported, tc.exported)
+			}
+		}
+	}
+}
+
 type testCase struct {
 	name     string
 	testFile string
@@ -321,6 +1260,54 @@ echo "Hello Dadl!"`,
 			},
 		},
 	},
+	{
+		name:     "overlay test",
+		testFile: "overlay/prod.dad",
+		expected: Node{
+			"name": "boutique",
+			"cassandra": Node{
+				"nodes": []interface{}{
+					Node{
+						"host": "prod1",
+						"port": 9042,
+					},
+					Node{
+						"host": "prod2",
+						"port": 9042,
+					},
+				},
+				"pass": "secret",
+				"user": "admin",
+			},
+			"tags": []interface{}{"prod"},
+		},
+	},
+	{
+		name:     "interpolation test",
+		testFile: "interpolation/interpolation.dad",
+		expected: Node{
+			"adminPort": 9042,
+			"cluster": Node{
+				"name": "prod",
+				"port": 9042,
+			},
+			"description": "Cluster prod costs ${price}",
+			"nodes": []interface{}{
+				Node{
+					"host": "prod-node1.example.com",
+					"port": 9042,
+				},
+				Node{
+					"host": "prod-node2.example.com",
+					"port": 9042,
+				},
+			},
+			"seed": Node{
+				"host": "prod-node1.example.com",
+				"port": 9042,
+			},
+		},
+	},
 	{
 		name:     "fragment test",
 		testFile: "fragment/fragment.dad",
@@ -329,4 +1316,22 @@ echo "Hello Dadl!"`,
 			"prop2": 5,
 		},
 	},
+	{
+		name:     "numbers test",
+		testFile: "numbers/numbers.dad",
+		expected: Node{
+			"ratio":       0.25,
+			"temperature": -150.0,
+			"distance":    mustParseBigFloat("1.000000000000000000000000000001e30"),
+			"scores":      []interface{}{1.0, 2.5, -3.0, 0.4},
+		},
+	},
+}
+
+func mustParseBigFloat(value string) *big.Float {
+	number, _, err := big.ParseFloat(value, 10, bigNumberPrecision, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return number
 }
@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+//WithResolvedRefs replaces values of ref type with a copy of the value they point to
+func WithResolvedRefs() Option {
+	return func(p *Parser) {
+		p.resolveRefs = true
+	}
+}
+
+//reference is a value of ref type checked once the whole tree, including imports, is built
+type reference struct {
+	path   string
+	target *query.Query
+	key    string
+	meta   parseMetadata
+}
+
+type refValue struct {
+	target *query.Query
+}
+
+func (v *refValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
+	log.Println("refValue [parse]:", value)
+	key := strings.TrimSpace(value)
+	builder.setSimpleValue(key)
+	if meta.session != nil {
+		meta.session.references = append(meta.session.references, &reference{
+			path:   builder.getPath(),
+			target: v.target,
+			key:    key,
+			meta:   meta,
+		})
+	}
+	return nil, nil
+}
+
+func (v *refValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
+	return nil, fmt.Errorf("[refValue] Not supported")
+}
+
+func (v *refValue) getChild(name string, builder valueBuilder, meta parseMetadata) (valueType, valueBuilder, error) {
+	return nil, nil, fmt.Errorf("[refValue] Not supported")
+}
+
+func (v *refValue) toRegex(ctx regexBuildContext) string {
+	return ".*?"
+}
+
+func (v *refValue) supportsChildren() bool {
+	return false
+}
+
+func (v *refValue) isSimpleValue() bool {
+	return true
+}
+
+//inheritReferences takes over references registered while parsing the base of an overlay,
+//references replaced by the overlay are dropped
+func (s *parseSession) inheritReferences(base *parseSession, overrides []Override) {
+	inherited := []*reference{}
+	for _, ref := range base.references {
+		if !isOverridden(ref.path, overrides) {
+			inherited = append(inherited, ref)
+		}
+	}
+	s.references = append(inherited, s.references...)
+}
+
+//checkReferences reports the first reference that does not match any key under its target.
+//When resolve is set references are replaced with a copy of the value they point to.
+func (s *parseSession) checkReferences(root Node, resolve bool) error {
+	resolved := make([]interface{}, len(s.references))
+	for i, ref := range s.references {
+		found := []interface{}{}
+		for _, match := range ref.target.Select(root) {
+			if asMap, ok := match.Value.(map[string]interface{}); ok {
+				if value, ok := asMap[ref.key]; ok {
+					found = append(found, value)
+				}
+			}
+		}
+		switch len(found) {
+		case 0:
+			return newParseErrorAt(ref.meta.position(), fmt.Sprintf("dangling reference: %s not found in %s", ref.key, ref.target))
+		case 1:
+			resolved[i] = deepCopy(found[0])
+		default:
+			if resolve {
+				return newParseErrorAt(ref.meta.position(), fmt.Sprintf("ambiguous reference: %s found more than once in %s", ref.key, ref.target))
+			}
+		}
+	}
+	if !resolve {
+		return nil
+	}
+	for i, ref := range s.references {
+		builder, err := builderAt(root, ref.path)
+		if err != nil {
+			return newParseErrorAt(ref.meta.position(), err.Error())
+		}
+		builder.setSimpleValue(resolved[i])
+	}
+	return nil
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			result[k] = deepCopy(v)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, v := range value {
+			result[i] = deepCopy(v)
+		}
+		return result
+	default:
+		return value
+	}
+}
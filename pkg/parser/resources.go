@@ -2,9 +2,15 @@ package parser
 
 import (
 	"io"
+	"io/fs"
+	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 )
 
 //ResourceProvider provides additional reources
@@ -46,3 +52,112 @@ func (p fsResourceProvider) ForResource(relativePath string) ResourceProvider {
 	log.Print("build provider for resource: ", relativePath)
 	return NewFSResourceProvider(filepath.Join(p.basePath, filepath.Dir(relativePath)))
 }
+
+//NewIOFSResourceProvider creates new resource provider reading resources from given file system, e.g. embed.FS.
+//Base path is a slash separated path of the directory inside the file system, "." is the root.
+func NewIOFSResourceProvider(fsys fs.FS, basePath string) ResourceProvider {
+	return ioFSResourceProvider{fsys: fsys, basePath: path.Clean(basePath)}
+}
+
+type ioFSResourceProvider struct {
+	fsys     fs.FS
+	basePath string
+}
+
+func (p ioFSResourceProvider) GetResource(relativePath string) (io.ReadCloser, error) {
+	return p.fsys.Open(path.Join(p.basePath, relativePath))
+}
+
+func (p ioFSResourceProvider) FindResources(pattern string) ([]string, error) {
+	log.Print("find resources: ", pattern, " in ", p.basePath)
+	res, err := fs.Glob(p.fsys, path.Join(p.basePath, pattern))
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range res {
+		res[i] = relativePath(p.basePath, r)
+	}
+	return res, nil
+}
+
+func (p ioFSResourceProvider) ForResource(relativePath string) ResourceProvider {
+	log.Print("build provider for resource: ", relativePath)
+	return NewIOFSResourceProvider(p.fsys, path.Join(p.basePath, path.Dir(relativePath)))
+}
+
+//MemoryResourceProvider provides resources stored in memory, it's useful for tests and documents built by programs
+type MemoryResourceProvider struct {
+	store    *memoryStore
+	basePath string
+}
+
+type memoryStore struct {
+	mu    sync.RWMutex
+	files map[string]string
+}
+
+//NewMemoryResourceProvider creates new resource provider with given files, keys are slash separated paths
+func NewMemoryResourceProvider(files map[string]string) *MemoryResourceProvider {
+	p := &MemoryResourceProvider{store: &memoryStore{files: map[string]string{}}, basePath: "."}
+	for name, content := range files {
+		p.Add(name, content)
+	}
+	return p
+}
+
+//Add adds or replaces resource, the name is relative to the provider the same way as in GetResource
+func (p *MemoryResourceProvider) Add(name string, content string) {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	p.store.files[path.Join(p.basePath, name)] = content
+}
+
+//GetResource returns content of the resource
+func (p *MemoryResourceProvider) GetResource(relativePath string) (io.ReadCloser, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	content, ok := p.store.files[path.Join(p.basePath, relativePath)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: relativePath, Err: fs.ErrNotExist}
+	}
+	return ioutil.NopCloser(strings.NewReader(content)), nil
+}
+
+//FindResources returns sorted names of resources matching the pattern, see path.Match for the syntax
+func (p *MemoryResourceProvider) FindResources(pattern string) ([]string, error) {
+	pattern = path.Join(p.basePath, pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	res := []string{}
+	for name := range p.store.files {
+		if matched, _ := path.Match(pattern, name); matched {
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res)
+	for i, r := range res {
+		res[i] = relativePath(p.basePath, r)
+	}
+	return res, nil
+}
+
+//ForResource returns provider resolving names relatively to the directory of given resource
+func (p *MemoryResourceProvider) ForResource(relativePath string) ResourceProvider {
+	return &MemoryResourceProvider{store: p.store, basePath: path.Join(p.basePath, path.Dir(relativePath))}
+}
+
+//relativePath returns slash separated target path relative to the base directory
+func relativePath(base string, target string) string {
+	if base == "." {
+		return target
+	}
+	baseParts, targetParts := strings.Split(base, "/"), strings.Split(target, "/")
+	common := 0
+	for common < len(baseParts) && common < len(targetParts)-1 && baseParts[common] == targetParts[common] {
+		common++
+	}
+	return path.Join(append([]string{strings.Repeat("../", len(baseParts)-common)}, targetParts[common:]...)...)
+}
@@ -0,0 +1,293 @@
+package parser
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIOFSResourceProvider(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/app.dad":           {Data: []byte("name app")},
+		"config/modules/cart.dad":  {Data: []byte("name cart")},
+		"config/modules/users.dad": {Data: []byte("name users")},
+		"config/modules/README.md": {Data: []byte("docs")},
+	}
+	resources := NewIOFSResourceProvider(fsys, "config")
+
+	paths, err := resources.FindResources("./modules/*.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"modules/cart.dad", "modules/users.dad"}; !reflect.DeepEqual(paths, expected) {
+		t.Errorf("GOT:  %v\nWANT: %v", paths, expected)
+	}
+
+	modules := resources.ForResource("modules/cart.dad")
+	file, err := modules.GetResource("users.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if data, _ := ioutil.ReadAll(file); string(data) != "name users" {
+		t.Errorf("unexpected content: %s", data)
+	}
+	file, err = modules.GetResource("../app.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if data, _ := ioutil.ReadAll(file); string(data) != "name app" {
+		t.Errorf("unexpected content: %s", data)
+	}
+}
+
+func TestParseFromIOFS(t *testing.T) {
+	parse := func(resources ResourceProvider) Node {
+		file, err := resources.GetResource("refs.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		parser := NewParser(WithFileName("refs.dad"))
+		res, err := parser.Parse(file, resources)
+		if err != nil {
+			t.Fatalf("could not parse refs.dad, %v", err)
+		}
+		return res
+	}
+	expected := parse(NewFSResourceProvider("../../samples/refs"))
+	if got := parse(NewIOFSResourceProvider(os.DirFS("../../samples"), "refs")); !reflect.DeepEqual(got, expected) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", got, expected)
+	}
+
+	files := []string{"refs.dad", "refs.dads", "modules/cart.dad", "modules/wishlist.dad"}
+	for _, archive := range []string{"refs.zip", "refs.tar.gz"} {
+		resources, err := NewArchiveResourceProvider(archive, buildArchive(t, archive, "../../samples/refs", files))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := parse(resources); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s\nGOT:  %+v\nWANT: %+v", archive, got, expected)
+		}
+	}
+}
+
+//buildArchive packs given files of the directory into zip or tar.gz archive
+func buildArchive(t *testing.T, archive string, dir string, files []string) []byte {
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	var zw *zip.Writer
+	var tw *tar.Writer
+	if strings.HasSuffix(archive, ".zip") {
+		zw = zip.NewWriter(&buf)
+	} else {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	}
+	for _, name := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if zw != nil {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(data)
+			continue
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(data)
+	}
+	if zw != nil {
+		zw.Close()
+	} else {
+		tw.Close()
+		gz.Close()
+	}
+	return buf.Bytes()
+}
+
+func TestMemoryResourceProvider(t *testing.T) {
+	resources := NewMemoryResourceProvider(map[string]string{
+		"project.dads": "@schema dadl 0.1\n\n[structure]\nname string\nmodules map[string]\n    name string\n",
+		"project.dad":  "@schema ./project.dads\n\nname shop\n\n[modules._ < ./modules/*.dad]\n",
+	})
+	resources.Add("modules/cart.dad", "@schema ../project.dads [modules._]\n\nname Cart\n")
+	resources.Add("modules/users.dad", "@schema ../project.dads [modules._]\n\nname Users\n")
+
+	if paths, err := resources.ForResource("modules/cart.dad").FindResources("../*.dads"); err != nil || !reflect.DeepEqual(paths, []string{"../project.dads"}) {
+		t.Errorf("unexpected resources: %v, %v", paths, err)
+	}
+	if _, err := resources.GetResource("missing.dad"); !os.IsNotExist(err) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	file, err := resources.GetResource("project.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	parser := NewParser(WithFileName("project.dad"))
+	got, err := parser.Parse(file, resources)
+	if err != nil {
+		t.Fatalf("could not parse project.dad, %v", err)
+	}
+	expected := Node{
+		"name": "shop",
+		"modules": Node{
+			"cart":  Node{"name": "Cart"},
+			"users": Node{"name": "Users"},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", got, expected)
+	}
+}
+
+func TestHTTPResourceProvider(t *testing.T) {
+	schema := "@schema dadl 0.1\n\n[structure]\nname string\nport int 0..65535\n"
+	sum := sha256.Sum256([]byte(schema))
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/schemas/app.dads" {
+			http.NotFound(w, r)
+			return
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		io.WriteString(w, schema)
+	}))
+	schemaURL := server.URL + "/schemas/app.dads"
+	cacheDir := t.TempDir()
+
+	parse := func(document string, options ...HTTPOption) (Node, *Dependencies, error) {
+		local := NewMemoryResourceProvider(map[string]string{"app.dad": document})
+		resources := NewHTTPResourceProvider(local, append([]HTTPOption{HTTPCacheDir(cacheDir)}, options...)...)
+		file, err := resources.GetResource("app.dad")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		dependencies := &Dependencies{}
+		parser := NewParser(WithFileName("app.dad"), WithDependencies(dependencies))
+		res, err := parser.Parse(file, resources)
+		return res, dependencies, err
+	}
+	expected := Node{"name": "app", "port": 8080}
+
+	pinned := "@schema " + schemaURL + " " + checksum + "\n\nname app\nport 8080\n"
+	got, dependencies, err := parse(pinned)
+	if err != nil {
+		t.Fatalf("could not parse app.dad, %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("GOT:  %+v\nWANT: %+v", got, expected)
+	}
+	if dependencies.Files[0].File != schemaURL {
+		t.Errorf("unexpected dependencies: %+v", dependencies.Files)
+	}
+
+	_, _, err = parse("@schema " + schemaURL + " sha256:" + strings.Repeat("0", 64) + "\n\nname app\n")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch for "+schemaURL) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, _, err = parse("@schema " + server.URL + "/schemas/missing.dads\n\nname app\n")
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	if got, _, err := parse(pinned); err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("cached schema not used on server error: %+v, %v", got, err)
+	}
+	status = http.StatusGone
+	_, _, err = parse(pinned)
+	if err == nil || !strings.Contains(err.Error(), "410 Gone") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	server.Close()
+	for _, options := range [][]HTTPOption{nil, {HTTPOffline()}} {
+		if got, _, err := parse(pinned, options...); err != nil || !reflect.DeepEqual(got, expected) {
+			t.Errorf("cached schema not used: %+v, %v", got, err)
+		}
+	}
+}
+
+func TestSandboxedFSResourceProvider(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	files := map[string]string{
+		"outside.dad":             "name outside\n",
+		"root/project.dads":       "@schema dadl 0.1\n\n[structure]\nmodules map[string]\n    name string\n",
+		"root/modules/cart.dad":   "@schema ../project.dads [modules._]\n\nname Cart\n",
+		"root/modules/README.md":  "docs\n",
+		"root/modules/notes.txt":  "name Notes\n",
+		"root/modules/nested.dad": "@schema ../project.dads [modules._]\n\nname Nested\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "outside.dad"), filepath.Join(root, "linked.dad")); err != nil {
+		t.Skip("symbolic links are not supported: ", err)
+	}
+
+	testCases := []struct {
+		importPath string
+		options    []SandboxOption
+		err        string
+	}{
+		{importPath: "./modules/*.dad"},
+		{importPath: "./linked.dad"},
+		{importPath: "./modules/*", options: []SandboxOption{SandboxExtensions(".dad", ".dads")}},
+		{importPath: "../outside.dad", err: "access to ../outside.dad denied: path is outside of the root directory"},
+		{importPath: "./modules/../../outside.dad", err: "access to ./modules/../../outside.dad denied: path is outside of the root directory"},
+		{importPath: filepath.Join(dir, "outside.dad"), err: "access to " + filepath.Join(dir, "outside.dad") + " denied: absolute paths are not allowed"},
+		{importPath: "./linked.dad", options: []SandboxOption{SandboxNoSymlinkEscapes()}, err: "access to ./linked.dad denied: symbolic link points outside of the root directory"},
+		{importPath: "./modules/notes.txt", options: []SandboxOption{SandboxExtensions(".dad", ".dads")}, err: "access to ./modules/notes.txt denied: extension is not allowed"},
+		{importPath: "https://example.com/modules/cart.dad", err: "access to https://example.com/modules/cart.dad denied: remote resources are not allowed"},
+	}
+	for _, tc := range testCases {
+		resources, err := NewSandboxedFSResourceProvider(root, tc.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		document := "@schema ./project.dads\n\n[modules._ < " + tc.importPath + "]\n"
+		parser := NewParser(WithFileName("app.dad"))
+		_, err = parser.Parse(strings.NewReader(document), resources)
+		expected := ""
+		if tc.err != "" {
+			expected = "Parse error [file: app.dad, line: 3, col: 0]: " + tc.err
+		}
+		if (err == nil && expected != "") || (err != nil && err.Error() != expected) {
+			t.Errorf("%s\nGOT:  %v\nWANT: %s", tc.importPath, err, expected)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//SandboxError is returned when a document refers to a resource it's not allowed to read
+type SandboxError struct {
+	Resource string
+	Reason   string
+}
+
+func (e *SandboxError) Error() string {
+	return fmt.Sprintf("access to %s denied: %s", e.Resource, e.Reason)
+}
+
+//SandboxOption configures sandboxed resource provider
+type SandboxOption func(s *sandbox)
+
+//SandboxNoSymlinkEscapes rejects resources that are symbolic links, or are inside linked directories, pointing
+//outside the root
+func SandboxNoSymlinkEscapes() SandboxOption {
+	return func(s *sandbox) {
+		s.noSymlinkEscapes = true
+	}
+}
+
+//SandboxExtensions allows only resources with given extensions, e.g. ".dad" and ".dads". Files with other
+//extensions are rejected when referred directly and skipped when matched by import patterns.
+func SandboxExtensions(extensions ...string) SandboxOption {
+	return func(s *sandbox) {
+		s.extensions = extensions
+	}
+}
+
+type sandbox struct {
+	root             string
+	realRoot         string
+	noSymlinkEscapes bool
+	extensions       []string
+}
+
+//NewSandboxedFSResourceProvider creates resource provider that reads files only inside the root directory.
+//Absolute paths, http and https URLs and relative paths leading outside the root are rejected with SandboxError.
+func NewSandboxedFSResourceProvider(root string, options ...SandboxOption) (ResourceProvider, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	s := &sandbox{root: absRoot}
+	for _, option := range options {
+		option(s)
+	}
+	if s.realRoot, err = filepath.EvalSymlinks(absRoot); err != nil {
+		return nil, err
+	}
+	return sandboxedFSResourceProvider{sandbox: s, dir: absRoot}, nil
+}
+
+type sandboxedFSResourceProvider struct {
+	sandbox *sandbox
+	//dir is an absolute path of the directory relative resources are resolved against
+	dir string
+}
+
+func (p sandboxedFSResourceProvider) GetResource(relativePath string) (io.ReadCloser, error) {
+	path, err := p.resolve(relativePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.check(relativePath, path); err != nil {
+		return nil, err
+	}
+	if err := p.sandbox.checkExtension(relativePath, path); err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (p sandboxedFSResourceProvider) FindResources(pattern string) ([]string, error) {
+	path, err := p.resolve(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.check(pattern, path); err != nil {
+		return nil, err
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		//resource referred directly is rejected instead of being skipped
+		if err := p.sandbox.checkExtension(pattern, path); err != nil {
+			return nil, err
+		}
+	}
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, match := range matches {
+		rel, err := filepath.Rel(p.dir, match)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.check(rel, match); err != nil {
+			return nil, err
+		}
+		if p.sandbox.checkExtension(rel, match) != nil {
+			continue
+		}
+		res = append(res, rel)
+	}
+	return res, nil
+}
+
+//ForResource returns provider for the directory of the resource, resources are still confined to the root
+func (p sandboxedFSResourceProvider) ForResource(relativePath string) ResourceProvider {
+	dir := p.dir
+	if path, err := p.resolve(relativePath); err == nil {
+		dir = filepath.Dir(path)
+	}
+	return sandboxedFSResourceProvider{sandbox: p.sandbox, dir: dir}
+}
+
+func (p sandboxedFSResourceProvider) resolve(relativePath string) (string, error) {
+	if isURL(relativePath) {
+		return "", &SandboxError{Resource: relativePath, Reason: "remote resources are not allowed"}
+	}
+	if filepath.IsAbs(relativePath) {
+		return "", &SandboxError{Resource: relativePath, Reason: "absolute paths are not allowed"}
+	}
+	return filepath.Join(p.dir, filepath.FromSlash(relativePath)), nil
+}
+
+//check verifies that resolved path of the resource is inside the root
+func (p sandboxedFSResourceProvider) check(name string, path string) error {
+	if !isInside(p.sandbox.root, path) {
+		return &SandboxError{Resource: name, Reason: "path is outside of the root directory"}
+	}
+	if p.sandbox.noSymlinkEscapes {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if err == nil && !isInside(p.sandbox.realRoot, real) {
+			return &SandboxError{Resource: name, Reason: "symbolic link points outside of the root directory"}
+		}
+	}
+	return nil
+}
+
+func (s *sandbox) checkExtension(name string, path string) error {
+	if len(s.extensions) == 0 {
+		return nil
+	}
+	for _, ext := range s.extensions {
+		if strings.HasSuffix(path, ext) {
+			return nil
+		}
+	}
+	return &SandboxError{Resource: name, Reason: "extension is not allowed"}
+}
+
+func isInside(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+//resourceError reports sandbox violations as parse errors at the line that refers to the resource
+func resourceError(err error, pos Position) error {
+	var sandboxErr *SandboxError
+	if errors.As(err, &sandboxErr) {
+		return newParseErrorAt(pos, sandboxErr.Error())
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+)
+
+//Schema is a compiled schema that can be reused to parse any number of documents
+type Schema struct {
+	fileName     string
+	checksum     [sha256.Size]byte
+	schema       DadlSchema
+	dependencies Dependencies
+}
+
+//CompileSchema reads and compiles schema with given name. The name is resolved with given resources and identifies
+//the schema the same way as names of schema files referred by documents parsed with WithFileName option.
+func CompileSchema(name string, resources ResourceProvider) (*Schema, error) {
+	data, err := readResource(name, resources)
+	if err != nil {
+		return nil, err
+	}
+	return compileSchema(name, data, resources.ForResource(name))
+}
+
+//WithSchema makes documents referring to the schema file with the same name and content use the compiled schema
+func WithSchema(schema *Schema) Option {
+	return func(p *Parser) {
+		p.schemas = append(p.schemas, schema)
+	}
+}
+
+//WithSchemaCache shares compiled schemas between parsers. By default schemas are cached only during a single parse.
+func WithSchemaCache(cache *SchemaCache) Option {
+	return func(p *Parser) {
+		p.schemaCache = cache
+	}
+}
+
+//SchemaCache stores compiled schemas by file name and checksum of the content, it's safe for concurrent use
+type SchemaCache struct {
+	mu      sync.Mutex
+	schemas map[schemaKey]*Schema
+}
+
+type schemaKey struct {
+	fileName string
+	checksum [sha256.Size]byte
+}
+
+//NewSchemaCache creates empty schema cache
+func NewSchemaCache() *SchemaCache {
+	return &SchemaCache{schemas: map[schemaKey]*Schema{}}
+}
+
+//Add stores compiled schema in the cache
+func (c *SchemaCache) Add(schema *Schema) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schemas[schemaKey{fileName: schema.fileName, checksum: schema.checksum}] = schema
+}
+
+//load returns cached schema if content of the schema file didn't change or compiles it again
+func (c *SchemaCache) load(schemaName string, fileName string, resources ResourceProvider, options ...Option) (*Schema, error) {
+	data, err := readResource(schemaName, resources)
+	if err != nil {
+		return nil, err
+	}
+	key := schemaKey{fileName: filepath.Clean(fileName), checksum: sha256.Sum256(data)}
+	c.mu.Lock()
+	schema, ok := c.schemas[key]
+	c.mu.Unlock()
+	if ok {
+		return schema, nil
+	}
+	schema, err = compileSchema(fileName, data, resources.ForResource(schemaName), options...)
+	if err != nil {
+		return nil, err
+	}
+	c.Add(schema)
+	return schema, nil
+}
+
+//compileSchema compiles schema content, resources are relative to the schema file
+func compileSchema(fileName string, data []byte, resources ResourceProvider, options ...Option) (*Schema, error) {
+	schema := &Schema{fileName: filepath.Clean(fileName), checksum: sha256.Sum256(data)}
+	var err error
+	schema.schema, err = parseSchema(data, resources, append(options, WithFileName(fileName), WithDependencies(&schema.dependencies))...)
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func readResource(name string, resources ResourceProvider) ([]byte, error) {
+	file, err := resources.GetResource(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+//Position points to a line in a source file
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%v:%v", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%v:%v:%v", p.File, p.Line, p.Column)
+}
+
+//SourceMap maps paths of parsed nodes to positions where they were defined
+type SourceMap map[string]Position
+
+//Lookup returns position of the node or of its closest ancestor with known position
+func (m SourceMap) Lookup(path string) (Position, bool) {
+	for {
+		if pos, ok := m[path]; ok {
+			return pos, true
+		}
+		parent, ok := query.Parent(path)
+		if !ok {
+			return Position{}, false
+		}
+		path = parent
+	}
+}
+
+func (m SourceMap) record(path string, pos Position) {
+	if m == nil || path == "" {
+		return
+	}
+	if _, ok := m[path]; !ok {
+		m[path] = pos
+	}
+}
+
+//merge adds positions recorded by a parser of concurrently parsed file
+func (m SourceMap) merge(other SourceMap) {
+	for path, pos := range other {
+		m.record(path, pos)
+	}
+}
@@ -0,0 +1,262 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+//EventKind describes what a streamed event reports
+type EventKind int
+
+//Event kinds
+const (
+	//EventStartNode starts a struct or a map
+	EventStartNode EventKind = iota
+	//EventEndNode ends a struct or a map
+	EventEndNode
+	//EventStartList starts a list
+	EventStartList
+	//EventEndList ends a list
+	EventEndList
+	//EventValue reports a simple value
+	EventValue
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventStartNode:
+		return "start node"
+	case EventEndNode:
+		return "end node"
+	case EventStartList:
+		return "start list"
+	case EventEndList:
+		return "end list"
+	case EventValue:
+		return "value"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+//Event is emitted for every node and value accepted by the parser while streaming a document
+type Event struct {
+	Kind EventKind
+	//Path of the node in the same syntax as used by query package
+	Path string
+	//Key of the map entry or struct field, empty for list items and the root
+	Key string
+	//Index of the list item, -1 for map entries and struct fields
+	Index int
+	//Value and Type of a simple value, e.g. "string", "int" or "bool"
+	Value interface{}
+	Type  string
+	//Position of the line where the node or value starts
+	Position Position
+}
+
+//EventHandler receives streamed events, returned error stops parsing
+type EventHandler func(event Event) error
+
+//Stream parses document without building the tree. Handler receives events in document order as lines are
+//accepted, so only nodes on the path to the current line are kept in memory. Nodes that are filled in again by
+//later groups or imports are started again. References and ${path} values are reported as written, they can't be
+//resolved without the tree, and @overlay isn't supported.
+func (p *Parser) Stream(reader io.Reader, resources ResourceProvider, handler EventHandler) error {
+	imports := p.importStack
+	if imports == nil {
+		imports = p.newImportStack()
+	}
+	s := &streamer{handler: handler, closedLists: map[string]int{}}
+	root := &streamBuilder{streamer: s, index: -1}
+	if err := s.emit(Event{Kind: EventStartNode, Index: -1, Position: Position{File: p.fileName, Line: 1}}); err != nil {
+		return err
+	}
+	s.open = []*openNode{{builder: root, kind: EventStartNode}}
+	err := p.parseDocument(reader, resources, root, nil, &documentContext{fileName: p.fileName, outline: p.outline, imports: imports, schemas: p.newSchemaCache()})
+	if err != nil {
+		return err
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	return s.closeTo(-1)
+}
+
+//lineObserver is implemented by builders that need to know which line is being parsed
+type lineObserver interface {
+	beginLine(pos Position) error
+}
+
+type openNode struct {
+	builder *streamBuilder
+	kind    EventKind
+	items   int
+}
+
+//streamer turns calls of value builders into events. It keeps stack of open nodes and the last simple value, which
+//is emitted only when parser moves on because multiline text is set again with every line.
+type streamer struct {
+	handler EventHandler
+	open    []*openNode
+	pending *Event
+	//checks of the pending value
+	checks []*valueCheck
+	pos    Position
+	//closedLists remembers sizes of lists so that lists filled in again by later groups continue numbering
+	closedLists map[string]int
+	err         error
+}
+
+func (s *streamer) beginLine(pos Position) error {
+	s.pos = pos
+	return s.err
+}
+
+func (s *streamer) emit(event Event) error {
+	if s.err == nil {
+		s.err = s.handler(event)
+	}
+	return s.err
+}
+
+func (s *streamer) flush() error {
+	if s.pending == nil {
+		return s.err
+	}
+	event := *s.pending
+	s.pending = nil
+	checks := s.checks
+	s.checks = nil
+	if s.err != nil {
+		return s.err
+	}
+	for _, check := range checks {
+		if text, ok := event.Value.(string); ok && check.path == event.Path {
+			if s.err = check.valueType.validate(text, check.meta); s.err != nil {
+				return s.err
+			}
+		}
+	}
+	return s.emit(event)
+}
+
+//closeTo ends nodes above given level of the stack
+func (s *streamer) closeTo(level int) error {
+	for len(s.open)-1 > level {
+		top := s.open[len(s.open)-1]
+		s.open = s.open[:len(s.open)-1]
+		kind := EventEndNode
+		if top.kind == EventStartList {
+			kind = EventEndList
+			s.closedLists[top.builder.path] = top.items
+		}
+		if top.builder.index >= 0 {
+			//items can't be filled in again, groups refer only to keys
+			for path := range s.closedLists {
+				if path != top.builder.path && isSameOrDescendant(path, top.builder.path) {
+					delete(s.closedLists, path)
+				}
+			}
+		}
+		s.emit(Event{Kind: kind, Path: top.builder.path, Key: top.builder.key, Index: top.builder.index, Position: s.pos})
+	}
+	return s.err
+}
+
+//enter makes the builder the innermost open node, nodes that are not its ancestors are ended
+func (s *streamer) enter(b *streamBuilder, kind EventKind) *openNode {
+	s.flush()
+	for i := len(s.open) - 1; i >= 0; i-- {
+		if s.open[i].builder.path == b.path {
+			s.closeTo(i)
+			return s.open[i]
+		}
+	}
+	if b.parent != nil {
+		s.enter(b.parent, b.parentKind)
+	}
+	node := &openNode{builder: b, kind: kind}
+	if kind == EventStartList {
+		node.items = s.closedLists[b.path]
+	}
+	s.open = append(s.open, node)
+	s.emit(Event{Kind: kind, Path: b.path, Key: b.key, Index: b.index, Position: s.pos})
+	return node
+}
+
+func (s *streamer) setValue(b *streamBuilder, value interface{}) {
+	if s.pending != nil && s.pending.Path == b.path {
+		s.pending.Value = value
+		return
+	}
+	s.enter(b.parent, b.parentKind)
+	s.pending = &Event{Kind: EventValue, Path: b.path, Key: b.key, Index: b.index, Value: value, Type: simpleTypeName(value), Position: s.pos}
+}
+
+//streamBuilder is a value builder that reports values to the streamer instead of storing them
+type streamBuilder struct {
+	streamer   *streamer
+	parent     *streamBuilder
+	parentKind EventKind
+	path       string
+	key        string
+	index      int
+}
+
+func (b *streamBuilder) getSimpleValue() interface{} {
+	if pending := b.streamer.pending; pending != nil && pending.Path == b.path {
+		return pending.Value
+	}
+	return nil
+}
+
+func (b *streamBuilder) setSimpleValue(value interface{}) {
+	b.streamer.setValue(b, value)
+}
+
+//getFieldBuilder doesn't start the node yet, parser asks for builders of nodes it's already inside of, e.g. to
+//continue a formula with children
+func (b *streamBuilder) getFieldBuilder(name string) valueBuilder {
+	return &streamBuilder{streamer: b.streamer, parent: b, parentKind: EventStartNode, path: query.Key(b.path, name), key: name, index: -1}
+}
+
+func (b *streamBuilder) getListItemBuilder() valueBuilder {
+	list := b.streamer.enter(b, EventStartList)
+	idx := list.items
+	list.items++
+	return &streamBuilder{streamer: b.streamer, parent: b, parentKind: EventStartList, path: query.Index(b.path, idx), index: idx}
+}
+
+func (b *streamBuilder) getPath() string {
+	return b.path
+}
+
+func (b *streamBuilder) deferCheck(check *valueCheck) {
+	b.streamer.checks = append(b.streamer.checks, check)
+}
+
+func (b *streamBuilder) beginLine(pos Position) error {
+	return b.streamer.beginLine(pos)
+}
+
+func simpleTypeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case int, *big.Int:
+		return "int"
+	case float64, *big.Float:
+		return "number"
+	case bool:
+		return "bool"
+	case time.Time:
+		return "time"
+	case time.Duration:
+		return "duration"
+	}
+	return fmt.Sprintf("%T", value)
+}
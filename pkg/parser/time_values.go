@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+)
+
+//timeKinds are layouts accepted by date, time and datetime types
+var timeKinds = map[string]struct {
+	regex   string
+	layouts []string
+}{
+	"date":     {regex: "[0-9]{4}-[0-9]{2}-[0-9]{2}", layouts: []string{"2006-01-02"}},
+	"time":     {regex: "[0-9]{2}:[0-9]{2}(?::[0-9]{2}(?:\\.[0-9]+)?)?", layouts: []string{"15:04:05", "15:04"}},
+	"datetime": {regex: "[0-9]{4}-[0-9]{2}-[0-9]{2}[Tt][0-9]{2}:[0-9]{2}:[0-9]{2}(?:\\.[0-9]+)?(?:[Zz]|[-+][0-9]{2}:[0-9]{2})", layouts: []string{time.RFC3339}},
+}
+
+//timeValue is a date, a time of day or a datetime with time zone parsed to time.Time. Dates and times are in UTC,
+//times are on January 1 of year 0 as returned by time.Parse, datetimes keep the offset as a fixed zone.
+type timeValue struct {
+	kind string
+	//min and max are inclusive, nil means no limit
+	min     *time.Time
+	max     *time.Time
+	minText string
+	maxText string
+}
+
+//parseTime parses value of given kind of time
+func parseTime(kind string, value string) (time.Time, error) {
+	for _, layout := range timeKinds[kind].layouts {
+		t, err := time.Parse(layout, strings.ToUpper(value))
+		if err != nil {
+			continue
+		}
+		if kind == "datetime" {
+			//offsets matching the local zone are parsed to it, the fixed zone keeps the result independent of the host
+			_, offset := t.Zone()
+			t = t.In(time.FixedZone("", offset))
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.New("Invalid " + kind + " value: " + value)
+}
+
+func (v *timeValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
+	log.Println("timeValue [parse]:", value)
+	value = strings.TrimSpace(value)
+	t, err := parseTime(v.kind, value)
+	if err != nil {
+		return nil, newParseErrorAt(meta.position(), err.Error())
+	}
+	if (v.min != nil && t.Before(*v.min)) || (v.max != nil && t.After(*v.max)) {
+		return nil, newParseErrorAt(meta.position(), strings.ToUpper(v.kind[:1])+v.kind[1:]+" out of range "+v.rangeString()+": "+value)
+	}
+	builder.setSimpleValue(t)
+	return nil, nil
+}
+
+func (v *timeValue) rangeString() string {
+	return v.minText + ".." + v.maxText
+}
+
+func (v *timeValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
+	return nil, errors.New("[timeValue] Not supported")
+}
+
+func (v *timeValue) getChild(name string, builder valueBuilder, meta parseMetadata) (valueType, valueBuilder, error) {
+	return nil, nil, errors.New("[timeValue] Not supported")
+}
+
+func (v *timeValue) toRegex(ctx regexBuildContext) string {
+	return timeKinds[v.kind].regex
+}
+
+func (v *timeValue) supportsChildren() bool {
+	return false
+}
+
+func (v *timeValue) isSimpleValue() bool {
+	return true
+}
+
+const durationRegex = "[-+]?(?:(?:[0-9]*\\.?[0-9]+(?:ns|us|µs|μs|ms|s|m|h))+|0|P(?:[0-9]+W|(?:[0-9]+D)?(?:T(?:[0-9]+H)?(?:[0-9]+M)?(?:[0-9]+(?:[.,][0-9]+)?S)?)?))"
+
+//durationValue is a duration in Go syntax, e.g. 1h30m, or ISO 8601 syntax, e.g. PT1H30M, parsed to time.Duration
+type durationValue struct {
+	//min and max are inclusive, nil means no limit
+	min     *time.Duration
+	max     *time.Duration
+	minText string
+	maxText string
+}
+
+//parseDuration parses Go and ISO 8601 durations. ISO durations can have weeks, days, hours, minutes and seconds,
+//days are 24 hours long. Years and months are rejected as their length isn't fixed.
+func parseDuration(value string) (time.Duration, error) {
+	invalid := errors.New("Invalid duration value: " + value)
+	text := value
+	negative := false
+	if strings.HasPrefix(text, "-") || strings.HasPrefix(text, "+") {
+		negative = text[0] == '-'
+		text = text[1:]
+	}
+	if !strings.HasPrefix(strings.ToUpper(text), "P") {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, invalid
+		}
+		return d, nil
+	}
+	text = strings.ToUpper(text[1:])
+	if text == "" || strings.HasSuffix(text, "T") {
+		return 0, invalid
+	}
+	total := new(big.Rat)
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	order := "WDHMS"
+	last := -1
+	for len(text) > 0 {
+		if text[0] == 'T' {
+			if _, inTime := units['H']; inTime {
+				return 0, invalid
+			}
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+			text = text[1:]
+			continue
+		}
+		end := strings.IndexAny(text, "WDHMSY")
+		if end <= 0 {
+			return 0, invalid
+		}
+		unit, ok := units[text[end]]
+		if !ok {
+			if text[end] == 'Y' || (text[end] == 'M' && units['D'] != 0) {
+				return 0, errors.New("Invalid duration value: " + value + ", years and months have no fixed length")
+			}
+			return 0, invalid
+		}
+		number := strings.Replace(text[:end], ",", ".", 1)
+		if strings.Contains(number, ".") && text[end] != 'S' {
+			return 0, invalid
+		}
+		amount, ok := new(big.Rat).SetString(number)
+		if !ok || strings.IndexAny(number, "+-eE/") >= 0 {
+			return 0, invalid
+		}
+		position := strings.IndexByte(order, text[end])
+		if position <= last {
+			return 0, invalid
+		}
+		last = position
+		total.Add(total, amount.Mul(amount, new(big.Rat).SetInt64(int64(unit))))
+		text = text[end+1:]
+	}
+	if negative {
+		total.Neg(total)
+	}
+	if !total.IsInt() || !total.Num().IsInt64() {
+		return 0, errors.New("Invalid duration value: " + value + ", it's out of range or more precise than nanoseconds")
+	}
+	return time.Duration(total.Num().Int64()), nil
+}
+
+func (v *durationValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
+	log.Println("durationValue [parse]:", value)
+	value = strings.TrimSpace(value)
+	d, err := parseDuration(value)
+	if err != nil {
+		return nil, newParseErrorAt(meta.position(), err.Error())
+	}
+	if (v.min != nil && d < *v.min) || (v.max != nil && d > *v.max) {
+		return nil, newParseErrorAt(meta.position(), "Duration out of range "+v.rangeString()+": "+value)
+	}
+	builder.setSimpleValue(d)
+	return nil, nil
+}
+
+func (v *durationValue) rangeString() string {
+	return v.minText + ".." + v.maxText
+}
+
+func (v *durationValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
+	return nil, errors.New("[durationValue] Not supported")
+}
+
+func (v *durationValue) getChild(name string, builder valueBuilder, meta parseMetadata) (valueType, valueBuilder, error) {
+	return nil, nil, errors.New("[durationValue] Not supported")
+}
+
+func (v *durationValue) toRegex(ctx regexBuildContext) string {
+	return durationRegex
+}
+
+func (v *durationValue) supportsChildren() bool {
+	return false
+}
+
+func (v *durationValue) isSimpleValue() bool {
+	return true
+}
@@ -1,12 +1,19 @@
 package parser
 
-import "log"
+import (
+	"log"
+
+	"github.com/dadlang/dadl/pkg/query"
+)
 
 type valueMeta struct {
 	meta map[string]interface{}
 }
 
 func (b *valueMeta) getMeta(name string) interface{} {
+	if b == nil {
+		return nil
+	}
 	return b.meta[name]
 }
 
@@ -22,10 +29,12 @@ type valueBuilder interface {
 	setSimpleValue(value interface{})
 	getFieldBuilder(name string) valueBuilder
 	getListItemBuilder() valueBuilder
+	getPath() string
 }
 
 type dynamicMapOrListValueBuilder struct {
 	value interface{}
+	path  string
 }
 
 func (b *dynamicMapOrListValueBuilder) getSimpleValue() interface{} {
@@ -44,6 +53,7 @@ func (b *dynamicMapOrListValueBuilder) getFieldBuilder(name string) valueBuilder
 	return &itemInMapValueBuilder{
 		parent:    b.value.(map[string]interface{}),
 		fieldName: name,
+		path:      query.Key(b.path, name),
 	}
 }
 
@@ -57,12 +67,18 @@ func (b *dynamicMapOrListValueBuilder) getListItemBuilder() valueBuilder {
 	return &itemInListValueBuilder{
 		parent: b.value.([]interface{}),
 		idx:    idx,
+		path:   query.Index(b.path, idx),
 	}
 }
 
+func (b *dynamicMapOrListValueBuilder) getPath() string {
+	return b.path
+}
+
 type itemInMapValueBuilder struct {
 	parent    map[string]interface{}
 	fieldName string
+	path      string
 }
 
 func (b *itemInMapValueBuilder) getSimpleValue() interface{} {
@@ -81,6 +97,7 @@ func (b *itemInMapValueBuilder) getFieldBuilder(name string) valueBuilder {
 	return &itemInMapValueBuilder{
 		parent:    b.parent[b.fieldName].(map[string]interface{}),
 		fieldName: name,
+		path:      query.Key(b.path, name),
 	}
 }
 
@@ -94,12 +111,18 @@ func (b *itemInMapValueBuilder) getListItemBuilder() valueBuilder {
 	return &itemInListValueBuilder{
 		parent: b.parent[b.fieldName].([]interface{}),
 		idx:    idx,
+		path:   query.Index(b.path, idx),
 	}
 }
 
+func (b *itemInMapValueBuilder) getPath() string {
+	return b.path
+}
+
 type itemInListValueBuilder struct {
 	parent []interface{}
 	idx    int
+	path   string
 }
 
 func (b *itemInListValueBuilder) getSimpleValue() interface{} {
@@ -118,6 +141,7 @@ func (b *itemInListValueBuilder) getFieldBuilder(name string) valueBuilder {
 	return &itemInMapValueBuilder{
 		parent:    b.parent[b.idx].(map[string]interface{}),
 		fieldName: name,
+		path:      query.Key(b.path, name),
 	}
 }
 
@@ -131,5 +155,10 @@ func (b *itemInListValueBuilder) getListItemBuilder() valueBuilder {
 	return &itemInListValueBuilder{
 		parent: b.parent[b.idx].([]interface{}),
 		idx:    idx,
+		path:   query.Index(b.path, idx),
 	}
 }
+
+func (b *itemInListValueBuilder) getPath() string {
+	return b.path
+}
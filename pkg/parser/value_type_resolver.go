@@ -2,16 +2,23 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/dadlang/dadl/pkg/query"
 )
 
 type typeResolver struct {
 	typesDefs     map[string]abstractTypeDef
+	typesDocs     map[string]string
 	resolvedTypes map[string]valueType
+	preparers     []preparer
 }
 
-func newResolver(typesDefs map[string]abstractTypeDef) *typeResolver {
-	return &typeResolver{typesDefs: typesDefs, resolvedTypes: map[string]valueType{}}
+func newResolver(typesDefs map[string]abstractTypeDef, typesDocs map[string]string) *typeResolver {
+	return &typeResolver{typesDefs: typesDefs, typesDocs: typesDocs, resolvedTypes: map[string]valueType{}}
 }
 
 func (r *typeResolver) buildFormulaItem(item abstractFormulaItem) (formulaItem, error) {
@@ -45,17 +52,21 @@ func (r *typeResolver) buildFormulaItem(item abstractFormulaItem) (formulaItem,
 func (r *typeResolver) buildType(typeDef abstractTypeDef) (valueType, error) {
 	switch typeDef := typeDef.(type) {
 	case *stringTypeDef:
-		regex := typeDef.Regex
-		if regex != "" {
-			return &stringValue{regex: regex}, nil
+		if typeDef.Regex != "" {
+			if _, err := regexp.Compile(typeDef.Regex); err != nil {
+				return nil, fmt.Errorf("invalid string pattern `%s`: %v", typeDef.Regex, err)
+			}
 		}
-		return &stringValue{}, nil
+		if typeDef.MaxLen > 0 && typeDef.MinLen > typeDef.MaxLen {
+			return nil, fmt.Errorf("minLen %d is greater than maxLen %d", typeDef.MinLen, typeDef.MaxLen)
+		}
+		return &stringValue{regex: typeDef.Regex, minLen: typeDef.MinLen, maxLen: typeDef.MaxLen, noTrim: typeDef.NoTrim}, nil
 	case *identifierTypeDef:
 		return &stringValue{regex: "[A-Za-z-0-9_-]+"}, nil
 	case *intTypeDef:
-		return &intValue{min: typeDef.Min, max: typeDef.Max}, nil
+		return newIntValue(typeDef.Kind, typeDef.Min, typeDef.Max), nil
 	case *numberTypeDef:
-		return &numberValue{}, nil
+		return &numberValue{big: typeDef.Big, min: typeDef.Min, max: typeDef.Max, minExclusive: typeDef.MinExclusive, maxExclusive: typeDef.MaxExclusive}, nil
 	case *boolTypeDef:
 		return &boolValue{}, nil
 	case *enumTypeDef:
@@ -87,13 +98,13 @@ func (r *typeResolver) buildType(typeDef abstractTypeDef) (valueType, error) {
 			}
 			items = append(items, item)
 		}
-		return &formulaValue{formula: items}, nil
+		return r.track(&formulaValue{formula: items}), nil
 	case *sequenceTypeDef:
 		itemType, err := r.buildType(typeDef.ItemType)
 		if err != nil {
 			return nil, err
 		}
-		return &sequenceValue{itemType: itemType}, nil
+		return r.track(&sequenceValue{itemType: itemType}), nil
 	// case "binaryDef":
 	// 	return &binaryValue{}, nil
 	case *listTypeDef:
@@ -115,14 +126,24 @@ func (r *typeResolver) buildType(typeDef abstractTypeDef) (valueType, error) {
 	case *structTypeDef:
 		var err error
 		children := map[string]valueType{}
+		docs := map[string]string{}
 		childerenDef := typeDef.Children
 		for key, def := range childerenDef {
 			children[key], err = r.buildType(def)
 			if err != nil {
 				return nil, err
 			}
+			if doc, ok := typeDef.Docs[key]; ok {
+				docs[key] = doc
+			} else if ref, ok := def.(*customTypeRef); ok && r.typesDocs[ref.TypeName] != "" {
+				docs[key] = r.typesDocs[ref.TypeName]
+			} else if format, ok := def.(*formatTypeDef); ok && r.typesDocs[format.Format] != "" {
+				docs[key] = r.typesDocs[format.Format]
+			} else if timeDef, ok := def.(*timeTypeDef); ok && r.typesDocs[timeDef.Kind] != "" {
+				docs[key] = r.typesDocs[timeDef.Kind]
+			}
 		}
-		return &structValue{children: children}, nil
+		return &structValue{children: children, docs: docs}, nil
 	case *oneofTypeDef:
 		optionsDef := typeDef.Options
 		options := make([]oneofValueOption, len(optionsDef))
@@ -133,7 +154,7 @@ func (r *typeResolver) buildType(typeDef abstractTypeDef) (valueType, error) {
 			}
 			options[i] = oneofValueOption{Name: typeName, ValueType: valueType}
 		}
-		return &oneofValue{options: options}, nil
+		return r.track(&oneofValue{options: options}), nil
 	case *complexTypeDef:
 		textType, err := r.buildType(typeDef.ValueType)
 		if err != nil {
@@ -152,12 +173,67 @@ func (r *typeResolver) buildType(typeDef abstractTypeDef) (valueType, error) {
 			structValueKey = ""
 		}
 		return &complexValue{textValue: textType, structValue: structureType, textValueKey: textValueKey, structValueKey: structValueKey}, nil
+	case *refTypeDef:
+		target, err := query.Compile(typeDef.Target)
+		if err != nil {
+			return nil, err
+		}
+		return &refValue{target: target}, nil
 	case *customTypeRef:
 		return r.resolveType(typeDef.TypeName)
+	case *timeTypeDef:
+		if _, ok := r.typesDefs[typeDef.Kind]; ok && typeDef.Min == "" && typeDef.Max == "" {
+			return r.resolveType(typeDef.Kind)
+		}
+		return buildTimeType(typeDef)
+	case *formatTypeDef:
+		if _, ok := r.typesDefs[typeDef.Format]; ok && len(typeDef.Schemes) == 0 {
+			return r.resolveType(typeDef.Format)
+		}
+		if len(typeDef.Schemes) > 0 && typeDef.Format != "uri" && typeDef.Format != "url" {
+			return nil, fmt.Errorf("schemes are supported only by uri and url, not by %s", typeDef.Format)
+		}
+		return &formatValue{name: typeDef.Format, format: stringFormats[typeDef.Format], schemes: typeDef.Schemes}, nil
 	}
 	return nil, errors.New("Unsupported type: " + reflect.TypeOf(typeDef).Name())
 }
 
+//buildTimeType parses bounds of the range with the type they limit
+func buildTimeType(typeDef *timeTypeDef) (valueType, error) {
+	if typeDef.Kind == "duration" {
+		res := &durationValue{minText: typeDef.Min, maxText: typeDef.Max}
+		for _, bound := range []struct {
+			text   string
+			target **time.Duration
+		}{{typeDef.Min, &res.min}, {typeDef.Max, &res.max}} {
+			if bound.text == "" {
+				continue
+			}
+			value, err := parseDuration(bound.text)
+			if err != nil {
+				return nil, errors.New("Invalid duration range bound: " + bound.text)
+			}
+			*bound.target = &value
+		}
+		return res, nil
+	}
+	res := &timeValue{kind: typeDef.Kind, minText: typeDef.Min, maxText: typeDef.Max}
+	for _, bound := range []struct {
+		text   string
+		target **time.Time
+	}{{typeDef.Min, &res.min}, {typeDef.Max, &res.max}} {
+		if bound.text == "" {
+			continue
+		}
+		value, err := parseTime(typeDef.Kind, bound.text)
+		if err != nil {
+			return nil, errors.New("Invalid " + typeDef.Kind + " range bound: " + bound.text)
+		}
+		*bound.target = &value
+	}
+	return res, nil
+}
+
 func (r *typeResolver) resolveType(typeName string) (valueType, error) {
 	if resolved, ok := r.resolvedTypes[typeName]; ok {
 		return resolved, nil
@@ -177,3 +253,19 @@ func (r *typeResolver) resolveType(typeName string) (valueType, error) {
 	r.resolvedTypes[typeName] = resolvedType
 	return r.resolvedTypes[typeName], nil
 }
+
+//track registers type that has to be prepared once all types are resolved
+func (r *typeResolver) track(v valueType) valueType {
+	if p, ok := v.(preparer); ok {
+		r.preparers = append(r.preparers, p)
+	}
+	return v
+}
+
+//prepare initializes all tracked types, it's called after the whole schema is built because types may refer to
+//custom types defined later
+func (r *typeResolver) prepare() {
+	for _, p := range r.preparers {
+		p.prepare()
+	}
+}
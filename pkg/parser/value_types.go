@@ -2,11 +2,17 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"log"
+	"math"
 	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/dadlang/dadl/pkg/query"
 )
 
 type regexBuildContext struct {
@@ -17,6 +23,11 @@ type parseMetadata struct {
 	fileName string
 	lineNo   int
 	colNo    int
+	session  *parseSession
+}
+
+func (m parseMetadata) position() Position {
+	return Position{File: m.fileName, Line: m.lineNo, Column: m.colNo}
 }
 
 type valueType interface {
@@ -28,6 +39,12 @@ type valueType interface {
 	isSimpleValue() bool
 }
 
+//preparer is implemented by value types that build matching regexes from their item types. Compiled schemas are
+//prepared when they are built so that parsing never modifies types shared between concurrent parsers.
+type preparer interface {
+	prepare()
+}
+
 type binaryAsTextFormat int
 
 const (
@@ -91,29 +108,130 @@ func (b *delegatedValueBuilder) setListElement(idx int, value interface{}) {
 }
 
 type stringValue struct {
-	regex      string
-	indentLock int
+	regex string
+	//minLen and maxLen limit number of characters, 0 means no limit
+	minLen int
+	maxLen int
+	//noTrim keeps whitespace around the value and indentation of multiline text relative to the line of the node
+	noTrim bool
+	once   sync.Once
+	re     *regexp.Regexp
+}
+
+func (v *stringValue) prepare() {
+	v.once.Do(func() {
+		if v.regex != "" {
+			v.re = regexp.MustCompile("^(?:" + v.regex + ")$")
+		}
+	})
 }
 
 func (v *stringValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
 	log.Println("stringValue [parse]:", value)
-	builder.setSimpleValue(strings.TrimSpace(value))
-	v.indentLock = -1
-	return nil, nil
+	if !v.noTrim {
+		value = strings.TrimSpace(value)
+	}
+	builder.setSimpleValue(value)
+	if err := v.check(builder, value, meta); err != nil {
+		return nil, err
+	}
+	//indentation of the first line of multiline text is removed from all lines
+	vMeta := &valueMeta{}
+	vMeta.setMeta("indentLock", -1)
+	return vMeta, nil
 }
 
 func (v *stringValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
 	log.Println("stringValue [parseChild]:", value)
-	if v.indentLock < 0 {
-		v.indentLock = calcIndentWeight(value)
+	indentLock, ok := valueMeta.getMeta("indentLock").(int)
+	if !ok || indentLock < 0 {
+		indentLock = calcIndentWeight(value)
+		if v.noTrim {
+			//only indentation of the line of the node is removed
+			indentLock, _ = valueMeta.getMeta("lineIndent").(int)
+		}
+		if valueMeta != nil {
+			valueMeta.setMeta("indentLock", indentLock)
+		}
 	}
-	value = value[v.indentLock:]
+	value = value[indentLock:]
 	if existingVal := builder.getSimpleValue(); existingVal != nil && existingVal != "" {
 		builder.setSimpleValue(existingVal.(string) + "\n" + value)
 	} else {
 		builder.setSimpleValue(value)
 	}
-	return &nodeInfo{valueType: v, builder: builder}, nil
+	return &nodeInfo{valueType: v, builder: builder, valueMeta: valueMeta}, nil
+}
+
+//check validates the value right away or, when it can be continued by multiline text, once the tree is built
+func (v *stringValue) check(builder valueBuilder, value string, meta parseMetadata) error {
+	if v.regex == "" && v.minLen == 0 && v.maxLen == 0 {
+		return nil
+	}
+	check := &valueCheck{path: builder.getPath(), valueType: v, meta: meta}
+	if meta.session != nil {
+		meta.session.checks = append(meta.session.checks, check)
+		return nil
+	}
+	if checker, ok := builder.(valueChecker); ok {
+		checker.deferCheck(check)
+		return nil
+	}
+	return v.validate(value, meta)
+}
+
+func (v *stringValue) validate(value string, meta parseMetadata) error {
+	v.prepare()
+	if v.re != nil && !v.re.MatchString(value) {
+		return newParseErrorAt(meta.position(), fmt.Sprintf("Value %q doesn't match pattern `%s`", value, v.regex))
+	}
+	length := utf8.RuneCountInString(value)
+	if v.minLen > 0 && length < v.minLen {
+		return newParseErrorAt(meta.position(), fmt.Sprintf("Value %q is shorter than %d characters", value, v.minLen))
+	}
+	if v.maxLen > 0 && length > v.maxLen {
+		return newParseErrorAt(meta.position(), fmt.Sprintf("Value %q is longer than %d characters", value, v.maxLen))
+	}
+	return nil
+}
+
+//valueCheck validates a value once the whole tree is built, multiline text is complete only then
+type valueCheck struct {
+	path      string
+	valueType *stringValue
+	meta      parseMetadata
+}
+
+//valueChecker is implemented by builders that validate values when they are complete
+type valueChecker interface {
+	deferCheck(check *valueCheck)
+}
+
+//inheritChecks keeps checks of the base document for values that were not replaced by the overlay
+func (s *parseSession) inheritChecks(base *parseSession, overrides []Override) {
+	inherited := []*valueCheck{}
+	for _, check := range base.checks {
+		if !isOverridden(check.path, overrides) {
+			inherited = append(inherited, check)
+		}
+	}
+	s.checks = append(inherited, s.checks...)
+}
+
+//checkValues reports the first value that doesn't satisfy constraints of its type
+func (s *parseSession) checkValues(root Node) error {
+	for _, check := range s.checks {
+		value, err := query.Get(root, check.path)
+		if err != nil {
+			return newParseErrorAt(check.meta.position(), fmt.Sprintf("can't check value of %s: %v", check.path, err))
+		}
+		if text, ok := value.(string); ok {
+			if err := check.valueType.validate(text, check.meta); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func (v *stringValue) toRegex(ctx regexBuildContext) string {
@@ -196,30 +314,111 @@ func (v *constantValue) isSimpleValue() bool {
 	return true
 }
 
+//intRegex matches decimal, hexadecimal, octal and binary literals with optional underscores between digits
+const intRegex = "[-+]?(?:0[xX][0-9a-fA-F_]+|0[oO][0-7_]+|0[bB][01_]+|\\d[\\d_]*)"
+
+var decimalIntRe = regexp.MustCompile(`^[-+]?\d+(?:_\d+)*$`)
+
+//intKindRanges holds ranges of sized int types, values of these types are parsed to Go types with the same name
+var intKindRanges = map[string][2]*big.Int{
+	"int8":   {big.NewInt(math.MinInt8), big.NewInt(math.MaxInt8)},
+	"int16":  {big.NewInt(math.MinInt16), big.NewInt(math.MaxInt16)},
+	"int32":  {big.NewInt(math.MinInt32), big.NewInt(math.MaxInt32)},
+	"int64":  {big.NewInt(math.MinInt64), big.NewInt(math.MaxInt64)},
+	"uint8":  {big.NewInt(0), big.NewInt(math.MaxUint8)},
+	"uint16": {big.NewInt(0), big.NewInt(math.MaxUint16)},
+	"uint32": {big.NewInt(0), big.NewInt(math.MaxUint32)},
+	"uint64": {big.NewInt(0), new(big.Int).SetUint64(math.MaxUint64)},
+}
+
 type intValue struct {
+	//kind is int or one of sized types like int8 or uint64
+	kind string
+	//min and max are nil for unbounded ranges
 	min *big.Int
 	max *big.Int
 }
 
-func (v *intValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
-	log.Println("intValue [parse]:", value)
-	if v.min != nil && v.max != nil && big.NewInt(-2147483648).Cmp(v.min) <= 0 && big.NewInt(2147483647).Cmp(v.max) >= 0 {
-		intValue, err := strconv.Atoi(value)
-		if err != nil {
-			return nil, err
+//newIntValue creates int type, range of sized types is narrowed by the range declared in the schema
+func newIntValue(kind string, min *big.Int, max *big.Int) *intValue {
+	v := &intValue{kind: kind, min: min, max: max}
+	if kindRange, ok := intKindRanges[kind]; ok {
+		if v.min == nil || v.min.Cmp(kindRange[0]) < 0 {
+			v.min = kindRange[0]
 		}
-		builder.setSimpleValue(intValue)
-	} else {
-		intValue := big.NewInt(0)
-		_, ok := intValue.SetString(value, 0)
-		if !ok {
-			return nil, newParseError(meta.lineNo, meta.colNo, "Invalid int value: "+value)
+		if v.max == nil || v.max.Cmp(kindRange[1]) > 0 {
+			v.max = kindRange[1]
 		}
-		builder.setSimpleValue(intValue)
 	}
+	return v
+}
+
+func (v *intValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
+	log.Println("intValue [parse]:", value)
+	value = strings.TrimSpace(value)
+	intValue, ok := parseIntLiteral(value)
+	if !ok {
+		return nil, newParseErrorAt(meta.position(), "Invalid int value: "+value)
+	}
+	if (v.min != nil && intValue.Cmp(v.min) < 0) || (v.max != nil && intValue.Cmp(v.max) > 0) {
+		return nil, newParseErrorAt(meta.position(), "Int out of range "+v.rangeString()+": "+value)
+	}
+	builder.setSimpleValue(v.goValue(intValue))
 	return nil, nil
 }
 
+//parseIntLiteral parses literals with 0x, 0o and 0b prefixes, numbers without prefix are decimal even with
+//leading zeros
+func parseIntLiteral(value string) (*big.Int, bool) {
+	if decimalIntRe.MatchString(value) {
+		return new(big.Int).SetString(strings.ReplaceAll(value, "_", ""), 10)
+	}
+	digits := strings.ToLower(strings.TrimLeft(value, "+-"))
+	if !strings.HasPrefix(digits, "0x") && !strings.HasPrefix(digits, "0o") && !strings.HasPrefix(digits, "0b") {
+		return nil, false
+	}
+	return new(big.Int).SetString(value, 0)
+}
+
+//goValue converts value to Go type of the kind, plain int uses int when the range fits 32 bits and *big.Int otherwise
+func (v *intValue) goValue(value *big.Int) interface{} {
+	switch v.kind {
+	case "int8":
+		return int8(value.Int64())
+	case "int16":
+		return int16(value.Int64())
+	case "int32":
+		return int32(value.Int64())
+	case "int64":
+		return value.Int64()
+	case "uint8":
+		return uint8(value.Uint64())
+	case "uint16":
+		return uint16(value.Uint64())
+	case "uint32":
+		return uint32(value.Uint64())
+	case "uint64":
+		return value.Uint64()
+	}
+	if v.min != nil && v.max != nil && big.NewInt(math.MinInt32).Cmp(v.min) <= 0 && big.NewInt(math.MaxInt32).Cmp(v.max) >= 0 {
+		return int(value.Int64())
+	}
+	return value
+}
+
+//rangeString describes the range the same way it's declared in the schema, e.g. 1..
+func (v *intValue) rangeString() string {
+	var sb strings.Builder
+	if v.min != nil {
+		sb.WriteString(v.min.String())
+	}
+	sb.WriteString("..")
+	if v.max != nil {
+		sb.WriteString(v.max.String())
+	}
+	return sb.String()
+}
+
 func (v *intValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
 	log.Println("intValue [parseChild]:", value)
 	return nil, errors.New("not supported")
@@ -230,7 +429,7 @@ func (v *intValue) getChild(name string, builder valueBuilder, meta parseMetadat
 }
 
 func (v *intValue) toRegex(ctx regexBuildContext) string {
-	return "(?:-)?\\d+"
+	return intRegex
 }
 
 func (v *intValue) supportsChildren() bool {
@@ -241,16 +440,95 @@ func (v *intValue) isSimpleValue() bool {
 	return true
 }
 
+//numberRegex matches decimal numbers with optional fraction and exponent, e.g. -0.5, 3. or 6.02e23
+const numberRegex = "[-+]?(?:\\d+(?:\\.\\d*)?|\\.\\d+)(?:[eE][-+]?\\d+)?"
+
+//numberRe checks syntax of the whole value, Go parsers accept also hex floats, underscores and infinity
+var numberRe = regexp.MustCompile("^(?:" + numberRegex + ")$")
+
+//bigNumberPrecision is precision in bits of numbers declared as big
+const bigNumberPrecision = 256
+
 type numberValue struct {
-	min big.Float
-	max big.Float
+	//big numbers are parsed to *big.Float, other to float64
+	big bool
+	//min and max are nil for unbounded ranges
+	min          *big.Float
+	max          *big.Float
+	minExclusive bool
+	maxExclusive bool
 }
 
 func (v *numberValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
-	builder.setSimpleValue(strings.TrimSpace(value))
+	log.Println("numberValue [parse]:", value)
+	value = strings.TrimSpace(value)
+	if !numberRe.MatchString(value) {
+		return nil, newParseErrorAt(meta.position(), "Invalid number value: "+value)
+	}
+	var number *big.Float
+	if v.big {
+		parsed, _, err := big.ParseFloat(value, 10, bigNumberPrecision, big.ToNearestEven)
+		if err != nil || parsed.IsInf() {
+			return nil, newParseErrorAt(meta.position(), "Invalid number value: "+value)
+		}
+		number = parsed
+		builder.setSimpleValue(parsed)
+	} else {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(parsed, 0) || math.IsNaN(parsed) {
+			return nil, newParseErrorAt(meta.position(), "Invalid number value: "+value)
+		}
+		number = big.NewFloat(parsed)
+		builder.setSimpleValue(parsed)
+	}
+	if !v.inRange(number) {
+		return nil, newParseErrorAt(meta.position(), "Number out of range "+v.rangeString()+": "+value)
+	}
 	return nil, nil
 }
 
+func (v *numberValue) inRange(number *big.Float) bool {
+	if v.min != nil {
+		if c := number.Cmp(v.bound(v.min)); c < 0 || c == 0 && v.minExclusive {
+			return false
+		}
+	}
+	if v.max != nil {
+		if c := number.Cmp(v.bound(v.max)); c > 0 || c == 0 && v.maxExclusive {
+			return false
+		}
+	}
+	return true
+}
+
+//bound rounds the bound to float64 unless numbers are big, so that e.g. 0.1 stays in range 0.1..1
+func (v *numberValue) bound(bound *big.Float) *big.Float {
+	if v.big {
+		return bound
+	}
+	rounded, _ := bound.Float64()
+	return big.NewFloat(rounded)
+}
+
+//rangeString describes the range the same way it's declared in the schema, e.g. 0<..1
+func (v *numberValue) rangeString() string {
+	var sb strings.Builder
+	if v.min != nil {
+		sb.WriteString(v.min.Text('g', -1))
+	}
+	if v.minExclusive {
+		sb.WriteString("<")
+	}
+	sb.WriteString("..")
+	if v.maxExclusive {
+		sb.WriteString("<")
+	}
+	if v.max != nil {
+		sb.WriteString(v.max.Text('g', -1))
+	}
+	return sb.String()
+}
+
 func (v *numberValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
 	return nil, errors.New("not supported")
 }
@@ -260,7 +538,7 @@ func (v *numberValue) getChild(name string, builder valueBuilder, meta parseMeta
 }
 
 func (v *numberValue) toRegex(ctx regexBuildContext) string {
-	return "-?(?:\\d+)|(?:\\d*\\.\\d+)"
+	return numberRegex
 }
 
 func (v *numberValue) supportsChildren() bool {
@@ -282,7 +560,7 @@ func (v *enumValue) parse(builder valueBuilder, value string, meta parseMetadata
 	if ok {
 		return v.valueType.parse(builder, mappedValue, meta)
 	} else {
-		return nil, newParseError(meta.lineNo, meta.colNo, "unsupported enum value: "+value)
+		return nil, newParseErrorAt(meta.position(), "unsupported enum value: "+value)
 	}
 }
 
@@ -326,6 +604,7 @@ type formulaItem struct {
 
 type formulaValue struct {
 	formula     []formulaItem
+	once        sync.Once
 	_re         *regexp.Regexp
 	_mapping    []formulaItem
 	_structItem *formulaItem
@@ -345,8 +624,8 @@ func buildMapping(mapping *[]formulaItem, items []formulaItem, structItem **form
 	}
 }
 
-func (v *formulaValue) initIfRequired() error {
-	if v._re == nil {
+func (v *formulaValue) prepare() {
+	v.once.Do(func() {
 		var sb strings.Builder
 		sb.WriteString("^")
 		sb.WriteString(buildItemsRegex(v.formula, true, newRegexBuildContext()))
@@ -355,13 +634,12 @@ func (v *formulaValue) initIfRequired() error {
 		v._re = regexp.MustCompile(sb.String())
 		v._mapping = []formulaItem{}
 		buildMapping(&v._mapping, v.formula, &v._structItem)
-	}
-	return nil
+	})
 }
 
 func (v *formulaValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
 	log.Println("formulaValue [parse]:", value)
-	v.initIfRequired()
+	v.prepare()
 	valueToParse := strings.TrimSpace(value)
 	match := v._re.FindStringSubmatchIndex(valueToParse)
 	var valueMeta *valueMeta
@@ -390,13 +668,13 @@ func (v *formulaValue) parse(builder valueBuilder, value string, meta parseMetad
 			}
 		}
 	} else {
-		return nil, newParseError(meta.lineNo, meta.colNo, "No match for: "+value)
+		return nil, newParseErrorAt(meta.position(), "No match for: "+value)
 	}
 	return valueMeta, nil
 }
 
 func (v *formulaValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
-	v.initIfRequired()
+	v.prepare()
 	log.Println("formulaValue [parseChild] struct type:", v._structItem)
 	if v._structItem != nil {
 		var newBuilder valueBuilder
@@ -460,12 +738,12 @@ func (v *formulaValue) isSimpleValue() bool {
 type sequenceValue struct {
 	itemType  valueType
 	separator string
+	once      sync.Once
 	re        *regexp.Regexp
 }
 
-func (v *sequenceValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
-	log.Println("sequenceValue [parse]:", value)
-	if v.re == nil {
+func (v *sequenceValue) prepare() {
+	v.once.Do(func() {
 		sep := regexp.QuoteMeta(v.separator)
 		if sep == "" {
 			sep = "\\s"
@@ -474,18 +752,23 @@ func (v *sequenceValue) parse(builder valueBuilder, value string, meta parseMeta
 		re := "^(" + v.itemType.toRegex(ctx) + ")(?:(?:" + sep + ")((?:" + v.itemType.toRegex(ctx) + ")(?:(?:" + sep + ")(?:" + v.itemType.toRegex(ctx) + "))*))?$"
 		log.Println("sequenceValue [regex]:", re)
 		v.re = regexp.MustCompile(re)
-	}
+	})
+}
+
+func (v *sequenceValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
+	log.Println("sequenceValue [parse]:", value)
+	v.prepare()
 
 	match := v.re.FindStringSubmatch(strings.TrimSpace(value))
 	if match == nil {
-		return nil, newParseError(meta.lineNo, meta.colNo, "sequenceValue [parse]: No match")
+		return nil, newParseErrorAt(meta.position(), "sequenceValue [parse]: No match")
 	}
 	matches := []string{}
 	matches = append(matches, match[1])
 	for match[2] != "" {
 		match = v.re.FindStringSubmatch(match[2])
 		if match == nil {
-			return nil, newParseError(meta.lineNo, meta.colNo, "sequenceValue [parse]: No match")
+			return nil, newParseErrorAt(meta.position(), "sequenceValue [parse]: No match")
 		}
 		matches = append(matches, match[1])
 	}
@@ -569,7 +852,7 @@ func (v *listValue) parseChild(builder valueBuilder, value string, valueMeta *va
 	log.Println("listValue [parseChild]:", value)
 
 	childBuilder := builder.getListItemBuilder()
-	vMata, err := v.childType.parse(childBuilder, value, meta)
+	vMata, err := parseValue(v.childType, childBuilder, value, meta)
 	if err != nil {
 		return nil, err
 	}
@@ -620,7 +903,7 @@ func (v *mapValue) parseChild(builder valueBuilder, value string, parentValueMet
 	var vMeta *valueMeta
 	var err error
 	if len(parts) > 1 {
-		vMeta, err = v.valueType.parse(childBuilder, parts[1], meta)
+		vMeta, err = parseValue(v.valueType, childBuilder, parts[1], meta)
 		if err != nil {
 			return nil, err
 		}
@@ -660,12 +943,13 @@ func (v *mapValue) isSimpleValue() bool {
 
 type structValue struct {
 	children map[string]valueType
+	docs     map[string]string
 }
 
 func (v *structValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
 	log.Println("structValue [parse]:", value)
 	if strings.TrimSpace(value) != "" {
-		return nil, newParseError(meta.lineNo, meta.colNo, "Unexpected value: "+value)
+		return nil, newParseErrorAt(meta.position(), "Unexpected value: "+value)
 	}
 	return nil, nil
 }
@@ -676,7 +960,7 @@ func (v *structValue) parseChild(builder valueBuilder, value string, valueMeta *
 	res := map[string]string{}
 	match := keyWithDelegatedValueRe.FindStringSubmatch(strings.TrimSpace(value))
 	if match == nil {
-		return nil, newParseError(meta.lineNo, meta.colNo, "Invalid format of child assignment")
+		return nil, newParseErrorAt(meta.position(), "Invalid format of child assignment")
 	}
 	if match != nil {
 		for i, name := range keyWithDelegatedValueRe.SubexpNames() {
@@ -690,16 +974,17 @@ func (v *structValue) parseChild(builder valueBuilder, value string, valueMeta *
 
 	if childType, ok := v.children[key]; ok {
 		childValueBuilder := builder.getFieldBuilder(key)
-		_, err := childType.parse(childValueBuilder, res["rest"], meta)
+		childMeta, err := parseValue(childType, childValueBuilder, res["rest"], meta)
 		if err != nil {
 			return nil, err
 		}
 		return &nodeInfo{
 			valueType: childType,
 			builder:   childValueBuilder,
+			valueMeta: childMeta,
 		}, nil
 	}
-	return nil, newParseError(meta.lineNo, meta.colNo, "Child not expected: "+key)
+	return nil, newParseErrorAt(meta.position(), "Child not expected: "+key)
 }
 
 func (v *structValue) toRegex(ctx regexBuildContext) string {
@@ -730,17 +1015,22 @@ type oneofValueOption struct {
 type oneofValue struct {
 	TypeKey string
 	options []oneofValueOption
+	once    sync.Once
 	_res    []*regexp.Regexp
 }
 
-func (v *oneofValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
-	log.Println("oneofValue [parse]:", value)
-	if len(v._res) == 0 {
+func (v *oneofValue) prepare() {
+	v.once.Do(func() {
 		v._res = make([]*regexp.Regexp, len(v.options))
 		for i, option := range v.options {
 			v._res[i] = regexp.MustCompile("^" + option.ValueType.toRegex(newRegexBuildContext()) + "$")
 		}
-	}
+	})
+}
+
+func (v *oneofValue) parse(builder valueBuilder, value string, meta parseMetadata) (*valueMeta, error) {
+	log.Println("oneofValue [parse]:", value)
+	v.prepare()
 
 	trimmedValue := strings.TrimSpace(value)
 
@@ -774,7 +1064,7 @@ func (v *oneofValue) parse(builder valueBuilder, value string, meta parseMetadat
 			return vMeta, nil
 		}
 	}
-	return nil, newParseError(meta.lineNo, meta.colNo, "No match for: "+value)
+	return nil, newParseErrorAt(meta.position(), "No match for: "+value)
 }
 
 func (v *oneofValue) parseChild(builder valueBuilder, value string, valueMeta *valueMeta, meta parseMetadata) (*nodeInfo, error) {
@@ -791,7 +1081,7 @@ func (v *oneofValue) parseChild(builder valueBuilder, value string, valueMeta *v
 		}
 		return lastOption.ValueType.parseChild(valueBuilder, value, valueMeta, meta)
 	}
-	return nil, newParseError(meta.lineNo, meta.colNo, "[oneofValue] Children not supported")
+	return nil, newParseErrorAt(meta.position(), "[oneofValue] Children not supported")
 }
 
 func (v *oneofValue) getChild(name string, builder valueBuilder, meta parseMetadata) (valueType, valueBuilder, error) {
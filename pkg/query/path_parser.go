@@ -0,0 +1,146 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type pathParser struct {
+	expr string
+	pos  int
+}
+
+func (p *pathParser) parse() ([]step, error) {
+	steps := []step{}
+	if p.expr == "" || p.expr == "." {
+		return steps, nil
+	}
+	first := true
+	for !p.eof() {
+		switch {
+		case strings.HasPrefix(p.expr[p.pos:], ".."):
+			p.pos += 2
+			name, err := p.readName()
+			if err != nil {
+				return nil, err
+			}
+			if name == "*" {
+				return nil, p.errorf("recursive wildcard is not supported")
+			}
+			steps = append(steps, step{kind: stepDescend, key: name})
+		case p.peek() == '.':
+			p.pos++
+			s, err := p.readNameStep()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		case p.peek() == '[':
+			s, err := p.readBracketStep()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		case first:
+			s, err := p.readNameStep()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		default:
+			return nil, p.errorf("unexpected character '%c'", p.peek())
+		}
+		first = false
+	}
+	return steps, nil
+}
+
+func (p *pathParser) readNameStep() (step, error) {
+	name, err := p.readName()
+	if err != nil {
+		return step{}, err
+	}
+	if name == "*" {
+		return step{kind: stepWildcard}, nil
+	}
+	return step{kind: stepKey, key: name}, nil
+}
+
+func (p *pathParser) readName() (string, error) {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(".[]", rune(p.peek())) {
+		p.pos++
+	}
+	name := strings.TrimSpace(p.expr[start:p.pos])
+	if name == "" {
+		return "", p.errorf("expected key name")
+	}
+	return name, nil
+}
+
+func (p *pathParser) readBracketStep() (step, error) {
+	p.pos++
+	end := p.closingBracket()
+	if end < 0 {
+		return step{}, p.errorf("missing closing bracket")
+	}
+	content := strings.TrimSpace(p.expr[p.pos:end])
+	p.pos = end + 1
+
+	switch {
+	case content == "*":
+		return step{kind: stepWildcard}, nil
+	case strings.HasPrefix(content, "?"):
+		pred, err := parsePredicate(strings.TrimSpace(content[1:]))
+		if err != nil {
+			return step{}, p.errorf("%v", err)
+		}
+		return step{kind: stepFilter, predicate: pred}, nil
+	case isQuoted(content):
+		return step{kind: stepKey, key: unescapeKey(content[1 : len(content)-1])}, nil
+	}
+	idx, err := strconv.Atoi(content)
+	if err != nil {
+		return step{}, p.errorf("invalid index: %s", content)
+	}
+	return step{kind: stepIndex, index: idx}, nil
+}
+
+func (p *pathParser) closingBracket() int {
+	quote := byte(0)
+	for i := p.pos; i < len(p.expr); i++ {
+		c := p.expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				//escaped character is skipped
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *pathParser) peek() byte {
+	return p.expr[p.pos]
+}
+
+func (p *pathParser) eof() bool {
+	return p.pos >= len(p.expr)
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid path %q at %d: %s", p.expr, p.pos, fmt.Sprintf(format, args...))
+}
+
+func isQuoted(value string) bool {
+	return len(value) >= 2 &&
+		((value[0] == '\'' && value[len(value)-1] == '\'') || (value[0] == '"' && value[len(value)-1] == '"'))
+}
@@ -0,0 +1,130 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var operators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+type predicate struct {
+	field    *Query
+	operator string
+	value    string
+}
+
+func parsePredicate(expr string) (*predicate, error) {
+	if expr == "" {
+		return nil, errors.New("empty predicate")
+	}
+	if idx, op := findOperator(expr); idx >= 0 {
+		field, err := Compile(strings.TrimSpace(expr[:idx]))
+		if err != nil {
+			return nil, err
+		}
+		value := strings.TrimSpace(expr[idx+len(op):])
+		if isQuoted(value) {
+			value = value[1 : len(value)-1]
+		}
+		return &predicate{field: field, operator: op, value: value}, nil
+	}
+	field, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &predicate{field: field}, nil
+}
+
+//findOperator returns position of the first operator outside of quoted keys and values, or -1
+func findOperator(expr string) (int, string) {
+	quote := byte(0)
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				//escaped character is skipped
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		default:
+			for _, op := range operators {
+				if strings.HasPrefix(expr[i:], op) {
+					return i, op
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+func (p *predicate) matches(node interface{}) bool {
+	matches := p.field.Select(node)
+	if p.operator == "" {
+		return len(matches) > 0
+	}
+	for _, m := range matches {
+		if p.compare(m.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *predicate) compare(value interface{}) bool {
+	if cmp, ok := compareNumbers(value, p.value); ok {
+		switch p.operator {
+		case "==":
+			return cmp == 0
+		case "!=":
+			return cmp != 0
+		case "<":
+			return cmp < 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		case ">=":
+			return cmp >= 0
+		}
+	}
+	text := fmt.Sprint(value)
+	switch p.operator {
+	case "==":
+		return text == p.value
+	case "!=":
+		return text != p.value
+	case "<":
+		return text < p.value
+	case "<=":
+		return text <= p.value
+	case ">":
+		return text > p.value
+	case ">=":
+		return text >= p.value
+	}
+	return false
+}
+
+func compareNumbers(value interface{}, literal string) (int, bool) {
+	right, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return 0, false
+	}
+	left, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case left < right:
+		return -1, true
+	case left > right:
+		return 1, true
+	}
+	return 0, true
+}
@@ -0,0 +1,279 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//Errors
+var (
+	ErrNotFound  = errors.New("no value matches given path")
+	ErrAmbiguous = errors.New("more than one value matches given path")
+)
+
+//Match is a single value selected by a query together with its path in the tree
+type Match struct {
+	Path  string
+	Value interface{}
+}
+
+//Query is a compiled path expression
+type Query struct {
+	expr  string
+	steps []step
+}
+
+type stepKind int
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepWildcard
+	stepDescend
+	stepFilter
+)
+
+type step struct {
+	kind      stepKind
+	key       string
+	index     int
+	predicate *predicate
+}
+
+//Compile parses a path expression.
+//Supported syntax:
+//  a.b.c            - map keys
+//  a[1], a[-1]      - list indices
+//  a.*, a[*]        - all children of a map or a list
+//  a..c             - recursive descent, every c below a
+//  a['b.c']         - quoted key, ' and \ inside quotes are escaped with \, e.g. a['it\'s']
+//  a[?port==9042]   - children matching a predicate (==, !=, <, <=, >, >= or plain existence)
+func Compile(expr string) (*Query, error) {
+	p := &pathParser{expr: strings.TrimSpace(expr)}
+	steps, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{expr: expr, steps: steps}, nil
+}
+
+//MustCompile is like Compile but panics if the expression cannot be parsed
+func MustCompile(expr string) *Query {
+	q, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+//String returns source expression of the query
+func (q *Query) String() string {
+	return q.expr
+}
+
+//Select returns all values matching the query in the order of appearance (map keys are sorted)
+func (q *Query) Select(root interface{}) []Match {
+	current := []Match{{Path: "", Value: root}}
+	for _, s := range q.steps {
+		next := []Match{}
+		for _, m := range current {
+			next = append(next, s.apply(m)...)
+		}
+		current = next
+	}
+	return current
+}
+
+//Get returns exactly one value matching the query
+func (q *Query) Get(root interface{}) (interface{}, error) {
+	matches := q.Select(root)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, q.expr)
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("%w: %s", ErrAmbiguous, q.expr)
+	}
+	return matches[0].Value, nil
+}
+
+//Select compiles the expression and returns all matching values
+func Select(root interface{}, expr string) ([]Match, error) {
+	q, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(root), nil
+}
+
+//Get compiles the expression and returns exactly one matching value
+func Get(root interface{}, expr string) (interface{}, error) {
+	q, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Get(root)
+}
+
+func (s step) apply(m Match) []Match {
+	switch s.kind {
+	case stepKey:
+		if asMap, ok := m.Value.(map[string]interface{}); ok {
+			if value, ok := asMap[s.key]; ok {
+				return []Match{{Path: Key(m.Path, s.key), Value: value}}
+			}
+		}
+	case stepIndex:
+		if asSlice, ok := m.Value.([]interface{}); ok {
+			idx := s.index
+			if idx < 0 {
+				idx += len(asSlice)
+			}
+			if idx >= 0 && idx < len(asSlice) {
+				return []Match{{Path: Index(m.Path, idx), Value: asSlice[idx]}}
+			}
+		}
+	case stepWildcard:
+		return children(m)
+	case stepDescend:
+		result := []Match{}
+		descend(m, func(d Match) {
+			result = append(result, step{kind: stepKey, key: s.key}.apply(d)...)
+		})
+		return result
+	case stepFilter:
+		result := []Match{}
+		for _, child := range children(m) {
+			if s.predicate.matches(child.Value) {
+				result = append(result, child)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func children(m Match) []Match {
+	switch value := m.Value.(type) {
+	case map[string]interface{}:
+		result := make([]Match, 0, len(value))
+		for _, key := range SortedKeys(value) {
+			result = append(result, Match{Path: Key(m.Path, key), Value: value[key]})
+		}
+		return result
+	case []interface{}:
+		result := make([]Match, 0, len(value))
+		for i, item := range value {
+			result = append(result, Match{Path: Index(m.Path, i), Value: item})
+		}
+		return result
+	}
+	return nil
+}
+
+func descend(m Match, visit func(Match)) {
+	visit(m)
+	for _, child := range children(m) {
+		descend(child, visit)
+	}
+}
+
+//SortedKeys returns keys of the map in alphabetical order
+func SortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//Key appends map key to the path, keys that are not plain are quoted with ' and backslash escapes
+func Key(path string, key string) string {
+	if !isPlainKey(key) {
+		return path + "['" + keyEscaper.Replace(key) + "']"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+//Index appends list index to the path
+func Index(path string, idx int) string {
+	return path + "[" + strconv.Itoa(idx) + "]"
+}
+
+//Parent returns the path of the parent node or false when the path points to the root
+func Parent(path string) (string, bool) {
+	if path == "" {
+		return "", false
+	}
+	depth := 0
+	quoted := false
+	for i := len(path) - 1; i >= 0; i-- {
+		switch c := path[i]; {
+		case c == '\'' && !isEscaped(path, i):
+			quoted = !quoted
+		case quoted:
+		case c == ']':
+			depth++
+		case c == '[':
+			depth--
+			if depth == 0 {
+				return path[:i], true
+			}
+		case c == '.' && depth == 0:
+			return path[:i], true
+		}
+	}
+	return "", true
+}
+
+//Base returns the last map key or list index of the path
+func Base(path string) string {
+	parent, ok := Parent(path)
+	if !ok {
+		return ""
+	}
+	segment := strings.TrimPrefix(path[len(parent):], ".")
+	if strings.HasPrefix(segment, "['") {
+		return unescapeKey(segment[2 : len(segment)-2])
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(segment, "["), "]")
+}
+
+var keyEscaper = strings.NewReplacer("\\", "\\\\", "'", "\\'")
+
+//unescapeKey removes backslashes escaping characters of a quoted key
+func unescapeKey(key string) string {
+	if !strings.Contains(key, "\\") {
+		return key
+	}
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' && i+1 < len(key) {
+			i++
+		}
+		sb.WriteByte(key[i])
+	}
+	return sb.String()
+}
+
+//isEscaped checks if character at given index is preceded by an odd number of backslashes
+func isEscaped(path string, idx int) bool {
+	escaped := false
+	for i := idx - 1; i >= 0 && path[i] == '\\'; i-- {
+		escaped = !escaped
+	}
+	return escaped
+}
+
+func isPlainKey(key string) bool {
+	if key == "" || key == "*" {
+		return false
+	}
+	return !strings.ContainsAny(key, ".[]'\\ \t")
+}
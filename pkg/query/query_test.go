@@ -0,0 +1,163 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var tree = map[string]interface{}{
+	"cassandra": map[string]interface{}{
+		"nodes": []interface{}{
+			map[string]interface{}{"host": "node1", "port": 9042},
+			map[string]interface{}{"host": "node2", "port": 9043},
+			map[string]interface{}{"host": "node3", "port": 9042},
+		},
+		"pass": "admin123",
+	},
+	"modules": map[string]interface{}{
+		"cart": map[string]interface{}{
+			"interactors": map[string]interface{}{"AddItem": map[string]interface{}{"name": "Add Item"}},
+		},
+		"sample": map[string]interface{}{
+			"interactors": map[string]interface{}{"GetItem": map[string]interface{}{"name": "Get Item"}},
+		},
+	},
+	"odd.key": "quoted",
+}
+
+func TestSelect(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected []Match
+	}{
+		{".", []Match{{"", tree}}},
+		{"cassandra.pass", []Match{{"cassandra.pass", "admin123"}}},
+		{"cassandra.nodes[1].port", []Match{{"cassandra.nodes[1].port", 9043}}},
+		{"cassandra.nodes[-1].host", []Match{{"cassandra.nodes[2].host", "node3"}}},
+		{"cassandra.nodes[5]", []Match{}},
+		{"cassandra.missing.key", []Match{}},
+		{"cassandra.nodes.port", []Match{}},
+		{"cassandra.nodes[*].host", []Match{
+			{"cassandra.nodes[0].host", "node1"},
+			{"cassandra.nodes[1].host", "node2"},
+			{"cassandra.nodes[2].host", "node3"},
+		}},
+		{"modules.*.interactors.*.name", []Match{
+			{"modules.cart.interactors.AddItem.name", "Add Item"},
+			{"modules.sample.interactors.GetItem.name", "Get Item"},
+		}},
+		{"..name", []Match{
+			{"modules.cart.interactors.AddItem.name", "Add Item"},
+			{"modules.sample.interactors.GetItem.name", "Get Item"},
+		}},
+		{"cassandra..host", []Match{
+			{"cassandra.nodes[0].host", "node1"},
+			{"cassandra.nodes[1].host", "node2"},
+			{"cassandra.nodes[2].host", "node3"},
+		}},
+		{"cassandra.nodes[?port==9042].host", []Match{
+			{"cassandra.nodes[0].host", "node1"},
+			{"cassandra.nodes[2].host", "node3"},
+		}},
+		{"cassandra.nodes[?port>9042].host", []Match{{"cassandra.nodes[1].host", "node2"}}},
+		{"cassandra.nodes[?host!='node1'].port", []Match{
+			{"cassandra.nodes[1].port", 9043},
+			{"cassandra.nodes[2].port", 9042},
+		}},
+		{"cassandra.nodes[?host<'node2==x'].host", []Match{
+			{"cassandra.nodes[0].host", "node1"},
+			{"cassandra.nodes[1].host", "node2"},
+		}},
+		{"modules[?interactors.AddItem]", []Match{{"modules.cart", tree["modules"].(map[string]interface{})["cart"]}}},
+		{"['odd.key']", []Match{{"['odd.key']", "quoted"}}},
+	}
+
+	for _, tc := range testCases {
+		got, err := Select(tree, tc.expr)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.expr, err)
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s\nGOT:  %+v\nWANT: %+v", tc.expr, got, tc.expected)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	value, err := Get(tree, "cassandra.nodes[0].port")
+	if err != nil || value != 9042 {
+		t.Errorf("unexpected result: %v, %v", value, err)
+	}
+	if _, err := Get(tree, "cassandra.nodes[*]"); !errors.Is(err, ErrAmbiguous) {
+		t.Errorf("expected ErrAmbiguous, got: %v", err)
+	}
+	if _, err := Get(tree, "cassandra.user"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got: %v", err)
+	}
+}
+
+func TestKeyRoundTrip(t *testing.T) {
+	for _, key := range []string{"it's", "a.b", "a[0]", "two words", "back\\slash", "quote\\'", "']"} {
+		child := map[string]interface{}{key: "value"}
+		data := map[string]interface{}{"root": child}
+		path := Key(Key("", "root"), key)
+		matches, err := Select(data, path)
+		if err != nil || len(matches) != 1 || matches[0].Value != "value" {
+			t.Errorf("%s: unexpected result: %v, %v", path, matches, err)
+		}
+		if base := Base(path); base != key {
+			t.Errorf("%s: got base %s, want %s", path, base, key)
+		}
+		if parent, ok := Parent(path); !ok || parent != "root" {
+			t.Errorf("%s: got parent %s, want root", path, parent)
+		}
+		if nested := Key(path, "child"); Base(nested) != "child" {
+			t.Errorf("%s: unexpected base of nested path", nested)
+		} else if parent, _ := Parent(nested); parent != path {
+			t.Errorf("%s: got parent %s, want %s", nested, parent, path)
+		}
+	}
+}
+
+func TestInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"a[", "a[x]", "a..", "a.[?]", "a]b"} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("expected error for: %s", expr)
+		}
+	}
+}
+
+func TestParent(t *testing.T) {
+	testCases := map[string]string{
+		"a.b.c":       "a.b",
+		"a.b[1]":      "a.b",
+		"a['x.y']":    "a",
+		"a[2]['x.y']": "a[2]",
+		"a":           "",
+	}
+	for path, expected := range testCases {
+		if got, ok := Parent(path); !ok || got != expected {
+			t.Errorf("%s: got %s, want %s", path, got, expected)
+		}
+	}
+	if _, ok := Parent(""); ok {
+		t.Errorf("root should not have a parent")
+	}
+}
+
+func TestBase(t *testing.T) {
+	testCases := map[string]string{
+		"a.b.c":       "c",
+		"a.b[1]":      "1",
+		"a['x.y']":    "x.y",
+		"a[2]['x.y']": "x.y",
+		"a":           "a",
+		"":            "",
+	}
+	for path, expected := range testCases {
+		if got := Base(path); got != expected {
+			t.Errorf("%s: got %s, want %s", path, got, expected)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+//Funcs returns helper functions available in templates:
+//	lower, upper, title, camel, pascal, snake, kebab - case conversion
+//	root - the root of the tree
+//	get <path> <value> - single value matching path relative to given value
+//	select <path> <value> - list of matches with Path and Value
+//	keys <map> - sorted keys of the map
+//	base <path> - last key or index of the path
+func Funcs(root interface{}) template.FuncMap {
+	return template.FuncMap{
+		"lower":  func(value interface{}) string { return strings.ToLower(fmt.Sprint(value)) },
+		"upper":  func(value interface{}) string { return strings.ToUpper(fmt.Sprint(value)) },
+		"title":  func(value interface{}) string { return joinWords(value, " ", strings.Title) },
+		"camel":  camel,
+		"pascal": func(value interface{}) string { return joinWords(value, "", strings.Title) },
+		"snake":  func(value interface{}) string { return joinWords(value, "_", strings.ToLower) },
+		"kebab":  func(value interface{}) string { return joinWords(value, "-", strings.ToLower) },
+		"root":   func() interface{} { return root },
+		"get":    func(path string, value interface{}) (interface{}, error) { return query.Get(value, path) },
+		"select": func(path string, value interface{}) ([]query.Match, error) { return query.Select(value, path) },
+		"keys":   keys,
+		"base":   query.Base,
+	}
+}
+
+func camel(value interface{}) string {
+	words := splitWords(fmt.Sprint(value))
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word)
+		} else {
+			words[i] = strings.Title(strings.ToLower(word))
+		}
+	}
+	return strings.Join(words, "")
+}
+
+func joinWords(value interface{}, separator string, convert func(string) string) string {
+	words := splitWords(fmt.Sprint(value))
+	for i, word := range words {
+		words[i] = convert(strings.ToLower(word))
+	}
+	return strings.Join(words, separator)
+}
+
+//splitWords splits text on separators and case changes, e.g. "HTTPServer_name" gives [HTTP Server name]
+func splitWords(text string) []string {
+	words := []string{}
+	runes := []rune(text)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextIsLower {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+func keys(value interface{}) ([]string, error) {
+	asMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("keys: %v is not a map", value)
+	}
+	return query.SortedKeys(asMap), nil
+}
@@ -0,0 +1,126 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/dadlang/dadl/pkg/query"
+)
+
+//TemplateExt is removed from names of rendered files
+const TemplateExt = ".tmpl"
+
+var placeholderRe = regexp.MustCompile(`\{([^{}]+)\}`)
+
+//File is a file generated from a template
+type File struct {
+	Path    string
+	Content string
+}
+
+//Template renders text with Go text/template. Name of the template is a path of the output file and may contain
+//{path} placeholders. Every placeholder is a path relative to the current value, starting with the root of the tree.
+//The template is rendered once for every value matched by the placeholder. Placeholder is replaced with the key
+//of a matched map or list value and such value becomes the current value, simple values are put into the name as is.
+func Template(root interface{}, name string, text string) ([]File, error) {
+	tmpl, err := template.New(name).Funcs(Funcs(root)).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	files := []File{}
+	err = expand("", name, root, func(path string, data interface{}) error {
+		var sb bytes.Buffer
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return err
+		}
+		files = append(files, File{Path: path, Content: sb.String()})
+		return nil
+	})
+	return files, err
+}
+
+//Dir renders all templates found in the directory. TemplateExt is removed from names of rendered files.
+func Dir(root interface{}, dir string) ([]File, error) {
+	names := []string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	files := []File{}
+	for _, name := range names {
+		text, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := Template(root, strings.TrimSuffix(name, TemplateExt), string(text))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, rendered...)
+	}
+	return files, nil
+}
+
+//Write saves files in the output directory
+func Write(files []File, outDir string) error {
+	for _, file := range files {
+		path := filepath.Join(outDir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(file.Content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//expand renders the template for every value matched by placeholders of the name, prefix is already expanded
+func expand(prefix string, name string, data interface{}, render func(path string, data interface{}) error) error {
+	loc := placeholderRe.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return render(prefix+name, data)
+	}
+	expr := strings.TrimSpace(name[loc[2]:loc[3]])
+	matches, err := query.Select(data, expr)
+	if err != nil {
+		return fmt.Errorf("invalid placeholder in %s: %v", name, err)
+	}
+	for _, match := range matches {
+		value := data
+		var replacement string
+		switch match.Value.(type) {
+		case map[string]interface{}, []interface{}:
+			replacement = query.Base(match.Path)
+			value = match.Value
+		default:
+			replacement = fmt.Sprint(match.Value)
+		}
+		if replacement == "" || replacement == "." || replacement == ".." || strings.ContainsAny(replacement, `/\`) {
+			return fmt.Errorf("placeholder {%s} of %s gives invalid file name: %q", expr, name, replacement)
+		}
+		if err := expand(prefix+name[:loc[0]]+replacement, name[loc[1]:], value, render); err != nil {
+			return err
+		}
+	}
+	return nil
+}
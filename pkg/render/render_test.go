@@ -0,0 +1,83 @@
+package render
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dadlang/dadl/pkg/parser"
+)
+
+func TestDir(t *testing.T) {
+	file, err := os.Open("../../samples/render/render.dad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	p := parser.NewParser()
+	tree, err := p.Parse(file, parser.NewFSResourceProvider("../../samples/render"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Dir(tree, "../../samples/render/templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	expected := []string{"README.md", "shoppingCart/AddItem.go", "shoppingCart/GetCart.go"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("GOT:  %v\nWANT: %v", paths, expected)
+	}
+	if !strings.Contains(files[0].Content, "- shopping-cart add_item get_cart") {
+		t.Errorf("unexpected README.md:\n%s", files[0].Content)
+	}
+	if !strings.Contains(files[1].Content, "type AddItemInput struct {\n\tProductId string\n\tUserId string\n}") {
+		t.Errorf("unexpected AddItem.go:\n%s", files[1].Content)
+	}
+}
+
+func TestTemplatePlaceholders(t *testing.T) {
+	tree := map[string]interface{}{
+		"version": 2,
+		"path":    "../etc",
+		"nodes":   []interface{}{map[string]interface{}{"host": "a"}, map[string]interface{}{"host": "b"}},
+	}
+	files, err := Template(tree, "v{version}/node{nodes[*]}.txt", "{{ .host }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []File{{Path: "v2/node0.txt", Content: "a"}, {Path: "v2/node1.txt", Content: "b"}}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("GOT:  %v\nWANT: %v", files, expected)
+	}
+	if _, err := Template(tree, "{path}.txt", ""); err == nil {
+		t.Errorf("placeholder should not escape output directory")
+	}
+}
+
+func TestCaseConversion(t *testing.T) {
+	funcs := Funcs(nil)
+	testCases := []struct {
+		fn       string
+		value    string
+		expected string
+	}{
+		{"camel", "HTTPServer name", "httpServerName"},
+		{"pascal", "add_item", "AddItem"},
+		{"snake", "GetCart", "get_cart"},
+		{"kebab", "shoppingCart", "shopping-cart"},
+		{"title", "online-boutique", "Online Boutique"},
+		{"upper", "v1Api", "V1API"},
+	}
+	for _, tc := range testCases {
+		got := funcs[tc.fn].(func(interface{}) string)(tc.value)
+		if got != tc.expected {
+			t.Errorf("%s(%s): got %s, want %s", tc.fn, tc.value, got, tc.expected)
+		}
+	}
+}